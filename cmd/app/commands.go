@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"goapptemplate/internal/app"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Build information set with -ldflags "-X main.version=... -X main.commit=... -X main.date=...",
+// module build info is used when unset
+var (
+	version string
+	commit  string
+	date    string
+)
+
+func newSeedCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Store sample books when there are none",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			n, err := app.Seed(cmd.Context(), cfg, force)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "stored %d books\n", n)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "store sample books even if there are books")
+	return cmd
+}
+
+func newConfigCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect configuration",
+	}
+	printCmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print effective configuration with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return app.PrintConfig(cmd.OutOrStdout(), cfg, format)
+		},
+	}
+	printCmd.Flags().StringVarP(&format, "format", "f", app.FormatYAML, "output format [yaml|json]")
+	cmd.AddCommand(
+		printCmd,
+		&cobra.Command{
+			Use:   "validate",
+			Short: "Validate configuration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				err = app.ValidateConfig(cfg)
+				if err != nil {
+					return fmt.Errorf("invalid config: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+func newOpenAPICmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "openapi",
+		Short: "Inspect OpenAPI specification",
+	}
+	dumpCmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Print OpenAPI specification",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return app.WriteOpenAPI(cmd.OutOrStdout(), cfg, format)
+		},
+	}
+	dumpCmd.Flags().StringVarP(&format, "format", "f", app.FormatJSON, "output format [json|yaml]")
+	cmd.AddCommand(dumpCmd)
+	return cmd
+}
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print build information",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			v, c, d, modified := version, commit, date, false
+			goVersion := "unknown"
+			if info, ok := debug.ReadBuildInfo(); ok {
+				goVersion = info.GoVersion
+				if v == "" {
+					v = info.Main.Version
+				}
+				for _, s := range info.Settings {
+					switch s.Key {
+					case "vcs.revision":
+						if c == "" {
+							c = s.Value
+						}
+					case "vcs.time":
+						if d == "" {
+							d = s.Value
+						}
+					case "vcs.modified":
+						modified = s.Value == "true"
+					}
+				}
+			}
+			if modified {
+				c += " (modified)"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "version: %s\ncommit:  %s\nbuilt:   %s\ngo:      %s\n", v, c, d, goVersion)
+		},
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"goapptemplate/config"
 	"goapptemplate/internal/app"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"log"
 
-	"github.com/spf13/pflag"
+	"github.com/spf13/cobra"
 )
 
+// configFilepath is the persistent --config flag value, env is read when empty
+var configFilepath string
+
 //	@title			Books API
 //	@version		0.1.0
 //	@description	Go app template books API.
@@ -19,16 +26,57 @@ import (
 //	@schemes	http https
 func main() {
 	// ________________________________________________________________________
-	// Parse cli args to config
-	filepath := pflag.StringP("config", "c", "", "configuration filepath (default: None)")
-	pflag.Parse()
+	// Setup commands, the service is served when no command is given
+	root := &cobra.Command{
+		Use:           "app",
+		Short:         "Books API service",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          serve,
+	}
+	root.PersistentFlags().StringVarP(&configFilepath, "config", "c", "", "configuration filepath (default: None)")
+	root.AddCommand(
+		newServeCmd(),
+		newMigrateCmd(),
+		newTenantCmd(),
+		newSeedCmd(),
+		newConfigCmd(),
+		newOpenAPICmd(),
+		newVersionCmd(),
+	)
 	// ________________________________________________________________________
-	// Load config
-	cfg, err := config.NewAppCfg(*filepath)
+	// Run command, commands other than serve are cancelled on termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := root.ExecuteContext(ctx)
 	if err != nil {
-		log.Fatalf("cannot load config: %s", err)
+		stop()
+		log.Fatalf("cannot run app: %s", err)
 	}
-	// ________________________________________________________________________
-	// Run app
-	app.Run(cfg)
+}
+
+func newServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve HTTP API",
+		Args:  cobra.NoArgs,
+		RunE:  serve,
+	}
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	return app.Run(cfg)
+}
+
+func loadConfig() (*config.AppCfg, error) {
+	cfg, err := config.NewAppCfg(configFilepath)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
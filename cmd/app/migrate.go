@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"goapptemplate/internal/app"
+	"goapptemplate/pkg/migrator"
+	"strconv"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage application schema migrations",
+	}
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+			if !dryRun {
+				return noChange(cmd, mu.Up(cmd.Context()))
+			}
+			pending, err := mu.DryRun(cmd.Context())
+			if err != nil {
+				return err
+			}
+			for _, m := range pending {
+				fmt.Fprintf(cmd.OutOrStdout(), "%-8d %s\n", m.Version, m.Identifier)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%d migrations applied and rolled back\n", len(pending))
+			return nil
+		}),
+	}
+	upCmd.Flags().BoolVar(&dryRun, "dry-run", false, "apply pending migrations in a transaction which is rolled back")
+	var confirm bool
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert all applied migrations, dropping all data",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !confirm {
+				return errors.New("reverting all migrations drops all data, pass --confirm to proceed")
+			}
+			return withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				return noChange(cmd, mu.Down(cmd.Context()))
+			})(cmd, args)
+		},
+	}
+	downCmd.Flags().BoolVar(&confirm, "confirm", false, "confirm dropping all data")
+	cmd.AddCommand(
+		upCmd,
+		downCmd,
+		&cobra.Command{
+			Use:   "steps N",
+			Short: "Apply N migrations up, or revert them down when N is negative, use -- -N",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				n, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid steps [%s]: %w", args[0], err)
+				}
+				return noChange(cmd, mu.Steps(cmd.Context(), n))
+			}),
+		},
+		&cobra.Command{
+			Use:   "pending",
+			Short: "Print SQL of migrations which are not applied yet",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				pending, err := mu.Pending()
+				if err != nil {
+					return err
+				}
+				for _, m := range pending {
+					fmt.Fprintf(cmd.OutOrStdout(), "-- %d %s\n%s\n", m.Version, m.Identifier, m.SQL)
+				}
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "verify",
+			Short: "Check that applied migrations are not changed since",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				mismatches, err := mu.Verify(cmd.Context())
+				if err != nil {
+					return err
+				}
+				for _, m := range mismatches {
+					fmt.Fprintln(cmd.OutOrStdout(), m)
+				}
+				if len(mismatches) > 0 {
+					return fmt.Errorf("[%d] applied migrations are changed", len(mismatches))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "applied migrations are not changed")
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "drift",
+			Short: "Compare schema tables, columns and indexes with the ones produced by applied migrations",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				drifts, err := mu.Drift(cmd.Context())
+				if err != nil {
+					return err
+				}
+				for _, d := range drifts {
+					fmt.Fprintln(cmd.OutOrStdout(), d)
+				}
+				if len(drifts) > 0 {
+					return fmt.Errorf("schema has drifted in [%d] objects", len(drifts))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "schema has not drifted")
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "goto VERSION",
+			Short: "Migrate up or down to version",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				v, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid version [%s]: %w", args[0], err)
+				}
+				return noChange(cmd, mu.Goto(cmd.Context(), uint(v)))
+			}),
+		},
+		&cobra.Command{
+			Use:   "force VERSION",
+			Short: "Set version without running migrations and clear dirty flag, use -- -1 for no version",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				v, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid version [%s]: %w", args[0], err)
+				}
+				return mu.Force(cmd.Context(), v)
+			}),
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print applied version",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				v, dirty, err := mu.Version()
+				if errors.Is(err, gomigrate.ErrNilVersion) {
+					fmt.Fprintln(cmd.OutOrStdout(), "no migrations applied")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if dirty {
+					fmt.Fprintf(cmd.OutOrStdout(), "%d (dirty)\n", v)
+					return nil
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), v)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Print available migrations and whether they are applied",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error {
+				current, dirty, err := mu.Version()
+				applied := err == nil
+				if err != nil && !errors.Is(err, gomigrate.ErrNilVersion) {
+					return err
+				}
+				versions, err := mu.Versions()
+				if err != nil {
+					return err
+				}
+				for _, v := range versions {
+					status := "pending"
+					switch {
+					case applied && v == current && dirty:
+						status = "dirty"
+					case applied && v <= current:
+						status = "applied"
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%-8d %s\n", v, status)
+				}
+				return nil
+			}),
+		},
+	)
+	return cmd
+}
+
+// withMigrator runs fn with migrator of application schema, which is closed afterwards.
+func withMigrator(fn func(cmd *cobra.Command, args []string, mu *migrator.PostgresMigrator) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		mu, err := app.NewMigrator(cfg)
+		if err != nil {
+			return err
+		}
+		defer mu.Close()
+		return fn(cmd, args, mu)
+	}
+}
+
+// noChange reports already applied migrations instead of failing.
+func noChange(cmd *cobra.Command, err error) error {
+	if errors.Is(err, gomigrate.ErrNoChange) {
+		fmt.Fprintln(cmd.OutOrStdout(), "no change")
+		return nil
+	}
+	return err
+}
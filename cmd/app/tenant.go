@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"goapptemplate/internal/app"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newTenantCmd() *cobra.Command {
+	var concurrency int
+	var confirm string
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Manage tenants with schema per tenant",
+	}
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending migrations to schemas of all tenants",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			results, err := app.MigrateTenants(cmd.Context(), cfg, concurrency)
+			if err != nil {
+				return err
+			}
+			failed := 0
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TENANT\tSCHEMA\tFROM\tTO\tSTATUS\tERROR")
+			for _, r := range results {
+				errMsg := ""
+				if r.Err != nil {
+					failed++
+					errMsg = r.Err.Error()
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", r.Tenant, r.Schema, r.From, r.To, r.Status, errMsg)
+			}
+			w.Flush()
+			if failed > 0 {
+				return fmt.Errorf("[%d] of [%d] tenants are not migrated", failed, len(results))
+			}
+			return nil
+		},
+	}
+	migrateCmd.Flags().IntVar(&concurrency, "concurrency", 0, "tenants migrated at once (default tenants.concurrency)")
+	deprovisionCmd := &cobra.Command{
+		Use:   "deprovision ID",
+		Short: "Unregister tenant and drop its schema with all data",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			err = app.DeprovisionTenant(cmd.Context(), cfg, args[0], confirm)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "deprovisioned tenant [%s]\n", args[0])
+			return nil
+		},
+	}
+	deprovisionCmd.Flags().StringVar(&confirm, "confirm", "", "tenant ID repeated to confirm dropping its data")
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "Print registered tenants",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				tenants, err := app.ListTenants(cmd.Context(), cfg)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "TENANT\tSCHEMA\tCREATED")
+				for _, t := range tenants {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", t.ID, t.Schema, t.CreatedAt.Format(time.RFC3339))
+				}
+				return w.Flush()
+			},
+		},
+		&cobra.Command{
+			Use:   "provision ID",
+			Short: "Create tenant schema, apply migrations to it and register tenant",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				t, err := app.ProvisionTenant(cmd.Context(), cfg, args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "provisioned tenant [%s] in schema [%s]\n", t.ID, t.Schema)
+				return nil
+			},
+		},
+		migrateCmd,
+		deprovisionCmd,
+	)
+	return cmd
+}
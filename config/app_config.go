@@ -6,12 +6,22 @@ import (
 )
 
 type AppCfg struct {
-	Logger   Logger   `json:"logger" yaml:"logger" env-prefix:"LOGGER_"`
-	HTTP     HTTP     `json:"http" yaml:"http" env-prefix:"HTTP_"`
-	TLS      TLS      `json:"tls" yaml:"tls" env-prefix:"TLS_"`
-	Postgres Postgres `json:"postgres" yaml:"postgres" env-prefix:"POSTGRES_"`
-	Redis    Redis    `json:"redis" yaml:"redis" env-prefix:"REDIS_"`
-	Swagger  Swagger  `json:"swagger" yaml:"swagger" env-prefix:"SWAGGER_"`
+	Logger     Logger     `json:"logger" yaml:"logger" env-prefix:"LOGGER_"`
+	HTTP       HTTP       `json:"http" yaml:"http" env-prefix:"HTTP_"`
+	TLS        TLS        `json:"tls" yaml:"tls" env-prefix:"TLS_"`
+	Postgres   Postgres   `json:"postgres" yaml:"postgres" env-prefix:"POSTGRES_"`
+	Migrations Migrations `json:"migrations" yaml:"migrations" env-prefix:"MIGRATIONS_"`
+	Tenants    Tenants    `json:"tenants" yaml:"tenants" env-prefix:"TENANTS_"`
+	Outbox     Outbox     `json:"outbox" yaml:"outbox" env-prefix:"OUTBOX_"`
+	Redis      Redis      `json:"redis" yaml:"redis" env-prefix:"REDIS_"`
+	Cache      Cache      `json:"cache" yaml:"cache" env-prefix:"CACHE_"`
+	RateLimit  RateLimit  `json:"rate_limit" yaml:"rateLimit" env-prefix:"RATE_LIMIT_"`
+	Security   Security   `json:"security" yaml:"security" env-prefix:"SECURITY_"`
+	Swagger    Swagger    `json:"swagger" yaml:"swagger" env-prefix:"SWAGGER_"`
+	Admin      Admin      `json:"admin" yaml:"admin" env-prefix:"ADMIN_"`
+	Lifecycle  Lifecycle  `json:"lifecycle" yaml:"lifecycle" env-prefix:"LIFECYCLE_"`
+
+	filepath string
 }
 
 func NewAppCfg(filepath string) (*AppCfg, error) {
@@ -28,5 +38,11 @@ func NewAppCfg(filepath string) (*AppCfg, error) {
 			return nil, errors.Wrap(err, "cannot read config")
 		}
 	}
+	c.filepath = filepath
 	return &c, err
 }
+
+// Reload reads configuration again from the same source.
+func (c *AppCfg) Reload() (*AppCfg, error) {
+	return NewAppCfg(c.filepath)
+}
@@ -4,3 +4,6 @@ import "embed"
 
 //go:embed migrations/app
 var MigrationsApp embed.FS
+
+//go:embed migrations/registry
+var MigrationsRegistry embed.FS
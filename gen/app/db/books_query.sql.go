@@ -11,20 +11,23 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const deleteBookWhereID = `-- name: DeleteBookWhereID :exec
+const deleteBookWhereID = `-- name: DeleteBookWhereID :execrows
 DELETE FROM books
 WHERE id = $1
 `
 
-func (q *Queries) DeleteBookWhereID(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteBookWhereID, id)
-	return err
+func (q *Queries) DeleteBookWhereID(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBookWhereID, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const insertBook = `-- name: InsertBook :one
 INSERT INTO books(id, name, description)
 VALUES ($1, $2, $3)
-RETURNING id, name, description, created_at, updated_at
+RETURNING id, name, description, created_at, updated_at, tenant_id
 `
 
 type InsertBookParams struct {
@@ -42,12 +45,13 @@ func (q *Queries) InsertBook(ctx context.Context, arg InsertBookParams) (*Book,
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TenantID,
 	)
 	return &i, err
 }
 
 const selectBookWhereID = `-- name: SelectBookWhereID :one
-SELECT id, name, description, created_at, updated_at
+SELECT id, name, description, created_at, updated_at, tenant_id
 FROM books
 WHERE id = $1
 `
@@ -61,12 +65,32 @@ func (q *Queries) SelectBookWhereID(ctx context.Context, id pgtype.UUID) (*Book,
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TenantID,
 	)
 	return &i, err
 }
 
-const selectBooks = `-- name: SelectBooks :many
-SELECT id, name, description, created_at, updated_at
+const selectBooksCount = `-- name: SelectBooksCount :one
+SELECT COUNT(*)
+FROM books
+WHERE name LIKE $1
+    AND description LIKE $2
+`
+
+type SelectBooksCountParams struct {
+	Name        string
+	Description pgtype.Text
+}
+
+func (q *Queries) SelectBooksCount(ctx context.Context, arg SelectBooksCountParams) (int64, error) {
+	row := q.db.QueryRow(ctx, selectBooksCount, arg.Name, arg.Description)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const selectBooksPage = `-- name: SelectBooksPage :many
+SELECT id, name, description, created_at, updated_at, tenant_id, COUNT(*) OVER() AS total
 FROM books
 WHERE name LIKE $1
     AND description LIKE $2
@@ -74,15 +98,25 @@ ORDER BY created_at DESC
 LIMIT $4 OFFSET $3
 `
 
-type SelectBooksParams struct {
+type SelectBooksPageParams struct {
 	Name        string
 	Description pgtype.Text
 	Ofst        int32
 	Lim         int32
 }
 
-func (q *Queries) SelectBooks(ctx context.Context, arg SelectBooksParams) ([]*Book, error) {
-	rows, err := q.db.Query(ctx, selectBooks,
+type SelectBooksPageRow struct {
+	ID          pgtype.UUID
+	Name        string
+	Description pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
+	TenantID    string
+	Total       int64
+}
+
+func (q *Queries) SelectBooksPage(ctx context.Context, arg SelectBooksPageParams) ([]*SelectBooksPageRow, error) {
+	rows, err := q.db.Query(ctx, selectBooksPage,
 		arg.Name,
 		arg.Description,
 		arg.Ofst,
@@ -92,15 +126,17 @@ func (q *Queries) SelectBooks(ctx context.Context, arg SelectBooksParams) ([]*Bo
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*Book
+	var items []*SelectBooksPageRow
 	for rows.Next() {
-		var i Book
+		var i SelectBooksPageRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.TenantID,
+			&i.Total,
 		); err != nil {
 			return nil, err
 		}
@@ -112,25 +148,6 @@ func (q *Queries) SelectBooks(ctx context.Context, arg SelectBooksParams) ([]*Bo
 	return items, nil
 }
 
-const selectBooksCount = `-- name: SelectBooksCount :one
-SELECT COUNT(*)
-FROM books
-WHERE name LIKE $1
-    AND description LIKE $2
-`
-
-type SelectBooksCountParams struct {
-	Name        string
-	Description pgtype.Text
-}
-
-func (q *Queries) SelectBooksCount(ctx context.Context, arg SelectBooksCountParams) (int64, error) {
-	row := q.db.QueryRow(ctx, selectBooksCount, arg.Name, arg.Description)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
-}
-
 const updateBookWhereID = `-- name: UpdateBookWhereID :exec
 UPDATE books
 SET name = $1,
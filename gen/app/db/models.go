@@ -14,4 +14,19 @@ type Book struct {
 	Description pgtype.Text
 	CreatedAt   pgtype.Timestamptz
 	UpdatedAt   pgtype.Timestamptz
+	TenantID    string
+}
+
+type Outbox struct {
+	ID            int64
+	AggregateType string
+	AggregateID   pgtype.UUID
+	EventType     string
+	TenantID      string
+	Payload       []byte
+	CreatedAt     pgtype.Timestamptz
+	Attempts      int32
+	NextAttemptAt pgtype.Timestamptz
+	LastError     pgtype.Text
+	PublishedAt   pgtype.Timestamptz
 }
@@ -2,12 +2,21 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"goapptemplate/config"
+	"goapptemplate/pkg/httpcache"
+	"goapptemplate/pkg/lifecycle"
+	"goapptemplate/pkg/listener"
+	"goapptemplate/pkg/migrator"
 	"goapptemplate/pkg/postgres"
+	"goapptemplate/pkg/sticky"
+	"goapptemplate/pkg/tenancy"
+	"goapptemplate/pkg/tlsconfig"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	swdocs "goapptemplate/docs"
@@ -19,40 +28,56 @@ import (
 	swagger "github.com/gofiber/swagger"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/mikhail-bigun/fiberlogrus"
 
-	"github.com/gofiber/fiber/v2/middleware/cache"
 	"github.com/gofiber/fiber/v2/middleware/compress"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/etag"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
-	"github.com/gofiber/helmet/v2"
-	"github.com/gofiber/storage/redis"
-	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
 
-func Run(cfg *config.AppCfg) {
+func Run(cfg *config.AppCfg) error {
 	// ________________________________________________________________________
 	// Setup logger
-	logger := newLogger(cfg)
+	logger, levels, closeLogger := newLogger(cfg)
 	// ________________________________________________________________________
-	// Migrate
-	err := migrate(cfg)
+	// Setup lifecycle, components are stopped in reverse order of registration
+	lc := lifecycle.New(logger)
+	lc.Append(lifecycle.Hook{
+		Name: "logger",
+		OnStop: func(ctx context.Context) error {
+			return closeLogger()
+		},
+	})
+	// fail logs err failing setup and stops components created so far, so that
+	// connections are closed and the error is flushed to error sink
+	fail := func(err error, msg string, fields logrus.Fields) error {
+		logger.WithError(err).WithFields(fields).Error(msg)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Lifecycle.ShutdownTimeout)
+		defer cancel()
+		return errors.Join(fmt.Errorf("%s: %w", msg, err), lc.Abort(ctx))
+	}
+	// ________________________________________________________________________
+	// Migrate or wait for migrations applied by another instance
+	err := migrate(cfg, logger)
 	if err != nil {
-		if errors.Is(err, gomigrate.ErrNoChange) {
-			logger.WithError(err).Warning("cannot migrate")
-		} else {
-			logger.WithError(err).Fatal("cannot migrate")
+		var dirty *migrator.DirtyError
+		if errors.As(err, &dirty) {
+			return fail(err, "Schema is dirty, fix it manually and run `app migrate force VERSION` before restart", logrus.Fields{
+				"version":  dirty.Version,
+				"previous": dirty.Previous,
+			})
 		}
-	} else {
-		logger.Info("Successfully applied migrations")
+		return fail(err, "cannot migrate", nil)
 	}
 	// ________________________________________________________________________
 	// Create Postgres database instance
-	pgxTracer := postgres.NewLogrusQueryTracer(logger)
+	pgxTracer := postgres.NewLogrusQueryTracer(logger, &postgres.TracerConfig{
+		SlowQueryThreshold: cfg.Postgres.Tracer.SlowQueryThreshold,
+		RedactArgs:         cfg.Postgres.Tracer.RedactArgs,
+	})
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	db, err := postgres.NewPostgresDB(
@@ -66,54 +91,171 @@ func Run(cfg *config.AppCfg) {
 		pgxTracer,
 	)
 	if err != nil {
-		logger.WithError(err).Fatal("cannot create postgres db")
+		return fail(err, "cannot create postgres db", nil)
+	}
+	db.Retry = postgresRetry(cfg)
+	lc.Append(lifecycle.Hook{
+		Name: "postgres",
+		OnStop: func(ctx context.Context) error {
+			db.Close()
+			return nil
+		},
+	})
+	if len(cfg.Postgres.Replicas.Hosts) > 0 {
+		db.Replicas, err = newReplicas(ctx, cfg, pgxTracer, logger)
+		if err != nil {
+			return fail(err, "cannot create postgres replicas", nil)
+		}
+		lc.Append(lifecycle.Hook{
+			Name: "replicas",
+			OnStop: func(ctx context.Context) error {
+				return db.Replicas.Close()
+			},
+		})
+	}
+	// ________________________________________________________________________
+	// Create cache storage
+	storage, err := newStorage(cfg)
+	if err != nil {
+		return fail(err, "cannot create cache storage", nil)
+	}
+	if storage != nil {
+		lc.Append(lifecycle.Hook{
+			Name: "storage",
+			OnStop: func(ctx context.Context) error {
+				return storage.Close()
+			},
+		})
+	}
+	// ________________________________________________________________________
+	// Create CORS and security headers middleware, misconfigured policies fail startup
+	sec, err := newSecurity(cfg)
+	if err != nil {
+		return fail(err, "cannot create security middleware", nil)
 	}
 	// ________________________________________________________________________
 	// Setup Fiber router
-	f := fiber.New()
+	f := fiber.New(fiber.Config{
+		// Listeners are logged instead of startup message of each of them
+		DisableStartupMessage:   true,
+		ProxyHeader:             cfg.HTTP.Proxy.Header,
+		EnableTrustedProxyCheck: len(cfg.HTTP.Proxy.Trusted) > 0,
+		TrustedProxies:          cfg.HTTP.Proxy.Trusted,
+	})
 	// Add middleware
 	f.Use(
-		fiberlogrus.New(fiberlogrus.Config{
-			Logger: logger,
-			Tags: []string{
-				fiberlogrus.TagLatency,
-				fiberlogrus.TagMethod,
-				fiberlogrus.TagURL,
-				fiberlogrus.TagUA,
-				fiberlogrus.TagBytesSent,
-				fiberlogrus.TagPid,
-				fiberlogrus.TagStatus,
-			},
-		}),
+		httpController.AccessLogger(logger, accessLogLocals()),
 		recover.New(),
+		listener.StripPrefix(),
 		compress.New(),
-		cors.New(),
-		helmet.New(),
+		// Headers are set before CORS, which ends preflight requests
+		sec.Headers(),
+		sec.CORS(),
 		requestid.New(),
+		httpController.ClientCertPrincipal(),
+		httpController.RequestLogger(logger),
 		etag.New(),
 		pprof.New(),
-		cache.New(cache.Config{
-			CacheControl: true,
-			Storage: redis.New(redis.Config{
-				Host:     cfg.Redis.Host,
-				Port:     int(cfg.Redis.Port),
-				Username: cfg.Redis.Username,
-				Password: cfg.Redis.Password,
-				Database: cfg.Redis.DB,
-			}),
-			Next: func(c *fiber.Ctx) bool {
-				return c.IP() == "127.0.0.1"
-			},
-		}),
 	)
+	var tenants usecase.TenantsRepo
+	if cfg.Tenants.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Migrations.Timeout)
+		if cfg.Tenants.Mode == tenantModeRow {
+			err = checkRowLevelSecurity(ctx, db)
+			if err != nil {
+				cancel()
+				return fail(err, "cannot isolate tenants, connect as a role without SUPERUSER and BYPASSRLS", nil)
+			}
+		}
+		var (
+			tm            *tenancy.Middleware
+			closeRegistry func()
+		)
+		tm, tenants, closeRegistry, err = newTenancy(ctx, cfg, logger)
+		cancel()
+		if err != nil {
+			return fail(err, "cannot create tenant resolution middleware", nil)
+		}
+		lc.Append(lifecycle.Hook{
+			Name: "tenants",
+			OnStop: func(ctx context.Context) error {
+				closeRegistry()
+				return nil
+			},
+		})
+		// Tenant requests use tenant schema, the rest of routes are not tenant scoped
+		f.Use(cfg.HTTP.FullAPIPath(), tm.Handler())
+	}
+	if db.Replicas != nil {
+		// Clients read from primary for a while after their writes
+		f.Use(cfg.HTTP.FullAPIPath(), sticky.New(&sticky.Config{
+			TTL:    cfg.Postgres.Replicas.Sticky.TTL,
+			Cookie: cfg.Postgres.Replicas.Sticky.Cookie,
+			Header: cfg.Postgres.Replicas.Sticky.Header,
+		}).Handler())
+	}
+	if cfg.RateLimit.Enabled {
+		rl, closer, err := newRateLimiter(cfg, storage, logger)
+		if err != nil {
+			return fail(err, "cannot create rate limiter", nil)
+		}
+		lc.Append(lifecycle.Hook{
+			Name: "ratelimit",
+			OnStop: func(ctx context.Context) error {
+				return closer.Close()
+			},
+		})
+		f.Use(rl.Handler())
+	}
+	if storage != nil {
+		f.Use(newHTTPCache(cfg, storage, logger).Handler())
+	}
 	// ________________________________________________________________________
 	// Setup Swagger docs
 	setupSwagger(f, cfg)
 	// ________________________________________________________________________
 	// Create Books repository
 	br := repo.NewBooksPostgresRepo(db, logger)
+	if cfg.Cache.Repo.Enabled && storage != nil {
+		br = repo.NewBooksCacheRepo(
+			br,
+			storage,
+			&repo.BooksCacheRepoConfig{
+				TTL:      cfg.Cache.Repo.TTL,
+				Jitter:   cfg.Cache.Repo.Jitter,
+				Cooldown: cfg.Cache.Repo.Cooldown,
+				Timeout:  cfg.Cache.Repo.Timeout,
+			},
+			logger,
+		)
+	}
 	// Create Books usecase
 	bu := usecase.NewBooks(br, logger)
+	// Create relay of book events written to outbox by repository
+	if cfg.Outbox.Enabled {
+		pub, err := newPublisher(cfg, logger)
+		if err != nil {
+			return fail(err, fmt.Sprintf("cannot create outbox publisher [%s]", cfg.Outbox.Publisher.Type), nil)
+		}
+		// Stopped after relay, which finishes batch being published
+		lc.Append(lifecycle.Hook{
+			Name: "publisher",
+			OnStop: func(ctx context.Context) error {
+				return pub.Close()
+			},
+		})
+		relay := newOutboxRelay(cfg, db, pub, tenants, logger)
+		lc.Append(lifecycle.Hook{
+			Name: "outbox",
+			OnStart: func(ctx context.Context) error {
+				lc.Go("outbox", relay.Run)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				return relay.Close()
+			},
+		})
+	}
 	// Create App HTTP controller
 	_ = httpController.NewAppHTTPController(
 		f,
@@ -124,6 +266,22 @@ func Run(cfg *config.AppCfg) {
 		},
 		logger,
 	)
+	// Create Admin HTTP controller
+	if cfg.Admin.Enabled {
+		if len(cfg.Admin.Principals) == 0 {
+			logger.Warn("Admin endpoints are not authorized, serve them on a private listener only")
+		}
+		_ = httpController.NewAdminHTTPController(
+			f,
+			levels,
+			pgxTracer,
+			&httpController.AdminHTTPControllerConfig{
+				BasePath:   cfg.HTTP.Prefix + cfg.Admin.Path,
+				Principals: cfg.Admin.Principals,
+			},
+			logger,
+		)
+	}
 	// ________________________________________________________________________
 	// Not found handler last in stack
 	f.Use(
@@ -131,62 +289,223 @@ func Run(cfg *config.AppCfg) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.ErrNotFound)
 		},
 	)
-	// Run Fiber router in a separate go routine
-	go func() {
-		err := runHTTP(f, cfg)
+	// ________________________________________________________________________
+	// Create TLS config, certificates are reloaded when their files change
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		reloader, err := tlsconfig.NewReloader(cfg.TLS.Cert.Filepath, cfg.TLS.Key.Filepath, cfg.TLS.ReloadInterval, logger)
 		if err != nil {
-			logger.WithError(err).Fatal("cannot run HTTP")
+			return fail(err, "cannot load tls certificate", nil)
 		}
-	}()
-	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
+		lc.Append(lifecycle.Hook{
+			Name: "tls",
+			OnStop: func(ctx context.Context) error {
+				return reloader.Close()
+			},
+		})
+		tlsConfig, err = tlsconfig.New(&tlsconfig.Config{
+			MinVersion:   cfg.TLS.MinVersion,
+			CipherSuites: cfg.TLS.CipherSuites,
+			ClientCAFile: cfg.TLS.ClientCA.Filepath,
+			ClientAuth:   cfg.TLS.ClientAuth,
+		}, reloader)
+		if err != nil {
+			return fail(err, "cannot create tls config", nil)
+		}
+	}
+	// Listen on every configured listener and serve Fiber router in separate go routines,
+	// all listeners are closed and in-flight requests are drained on stop
+	listenerConfigs, err := newListenerConfigs(cfg, tlsConfig)
+	if err != nil {
+		return fail(err, "cannot create listeners config", nil)
+	}
+	lc.Append(lifecycle.Hook{
+		Name: "http",
+		OnStart: func(ctx context.Context) error {
+			listeners, err := listener.ListenAll(listenerConfigs)
+			if err != nil {
+				return err
+			}
+			for _, l := range listeners {
+				l := l
+				logger.WithField("listener", l.String()).Info("Serving HTTP")
+				lc.Go("http", func() error {
+					return f.Listener(l)
+				})
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return f.ShutdownWithContext(ctx)
+		},
+	})
+	// ________________________________________________________________________
+	// Start components
+	ctx, cancel = context.WithTimeout(context.Background(), cfg.Lifecycle.StartTimeout)
+	defer cancel()
+	err = lc.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start service: %w", err)
+	}
+	// Wait for termination signal or a component failure to gracefully shutdown the service.
 	// Use a buffered channel to avoid missing signals as recommended for signal.Notify
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	// This blocks the main thread until an interrupt is received
-	<-quit
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(quit)
+	// This blocks the main thread until a termination signal is received,
+	// SIGHUP reloads logger levels from configuration
+	var runErr error
+wait:
+	for {
+		select {
+		case sig := <-quit:
+			if sig != syscall.SIGHUP {
+				logger.WithField("signal", sig.String()).Info("Received termination signal")
+				break wait
+			}
+			err := reloadLevels(cfg, levels)
+			if err != nil {
+				logger.WithError(err).Error("cannot reload logger levels")
+				continue
+			}
+			logger.Info("Reloaded logger levels")
+		case runErr = <-lc.Err():
+			logger.WithError(runErr).Error("Service component failed")
+			break wait
+		}
+	}
 	logger.Info("Gracefully shutting down...")
-	err = f.Shutdown()
+	ctx, cancel = context.WithTimeout(context.Background(), cfg.Lifecycle.ShutdownTimeout)
+	defer cancel()
+	logger.Info("Running cleanup tasks...")
+	err = lc.Stop(ctx)
 	if err != nil {
-		logger.WithError(err).Fatal("cannot gracefully shutdown Fiber server")
+		return fmt.Errorf("cannot gracefully shutdown service: %w", err)
+	}
+	if runErr != nil {
+		return runErr
 	}
-	logger.Info("Running cleanup tasks...")
 	logger.Info("Service shutdown successfully")
+	return nil
 }
 
-func newLogger(cfg *config.AppCfg) *logrus.Logger {
-	logger := logrus.New()
-	lvl, err := logrus.ParseLevel(cfg.Logger.Level)
+// postgresRetry returns retry of transactions failed with serialization failure or deadlock.
+func postgresRetry(cfg *config.AppCfg) postgres.Retry {
+	return postgres.Retry{
+		Retries:    cfg.Postgres.Tx.Retries,
+		Backoff:    cfg.Postgres.Tx.Backoff,
+		MaxBackoff: cfg.Postgres.Tx.MaxBackoff,
+	}
+}
+
+// newReplicas creates read replicas of application schema.
+func newReplicas(ctx context.Context, cfg *config.AppCfg, tracer pgx.QueryTracer, logger *logrus.Logger) (*postgres.Replicas, error) {
+	configs, err := cfg.Postgres.ReplicaConfigStrings(
+		fmt.Sprintf(
+			"search_path=%s",
+			domain.SchemaApp,
+		),
+	)
 	if err != nil {
-		logger.WithError(err).Fatalf("cannot parse logrus level [%s]", cfg.Logger.Level)
-	}
-	logger.SetLevel(lvl)
-	logger.Formatter = &logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "",
-		// DisableSorting:         true,
-		DisableLevelTruncation: true,
-		PadLevelText:           true,
-		QuoteEmptyFields:       true,
-	}
-	return logger
+		return nil, err
+	}
+	return postgres.NewReplicas(ctx, configs, tracer, &postgres.ReplicasConfig{
+		MaxLag:   cfg.Postgres.Replicas.MaxLag,
+		Interval: cfg.Postgres.Replicas.Interval,
+	}, logger)
 }
 
-func runHTTP(f *fiber.App, cfg *config.AppCfg) error {
-	if cfg.TLS.Cert.Filepath != "" &&
-		cfg.TLS.Key.Filepath != "" {
-		return f.ListenTLS(
-			cfg.HTTP.Addr(),
-			cfg.TLS.Cert.Filepath,
-			cfg.TLS.Key.Filepath,
-		)
-	} else {
-		return f.Listen(cfg.HTTP.Addr())
+// newListenerConfigs returns configured listeners, a single tcp listener on HTTP
+// host and port is used when none are configured.
+func newListenerConfigs(cfg *config.AppCfg, tlsConfig *tls.Config) ([]*listener.Config, error) {
+	if len(cfg.HTTP.Listeners) == 0 {
+		return []*listener.Config{{
+			Network: listener.NetworkTCP,
+			Address: cfg.HTTP.Addr(),
+			TLS:     tlsConfig,
+		}}, nil
+	}
+	configs := make([]*listener.Config, 0, len(cfg.HTTP.Listeners))
+	for _, l := range cfg.HTTP.Listeners {
+		c := &listener.Config{
+			Network: l.Network,
+			Address: l.Address,
+			Prefix:  l.Prefix,
+		}
+		if l.TLS {
+			if tlsConfig == nil {
+				return nil, fmt.Errorf("listener [%s] [%s] requires tls cert and key", l.Network, l.Address)
+			}
+			c.TLS = tlsConfig
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
+}
+
+func newHTTPCache(cfg *config.AppCfg, storage fiber.Storage, logger *logrus.Logger) *httpcache.Cache {
+	routes := make([]httpcache.Route, 0, len(cfg.Cache.Routes))
+	for path, ttl := range cfg.Cache.Routes {
+		routes = append(routes, httpcache.Route{
+			Path: cfg.HTTP.FullAPIPath() + path,
+			TTL:  ttl,
+		})
+	}
+	prefixed := func(paths []string) []string {
+		res := make([]string, 0, len(paths))
+		for _, p := range paths {
+			res = append(res, cfg.HTTP.Prefix+p)
+		}
+		return res
 	}
+	var principal func(*fiber.Ctx) string
+	if cfg.Cache.Policy.Scope == cachePolicyScopePrincipal {
+		principal = httpController.Principal
+	}
+	var tenant func(*fiber.Ctx) string
+	if cfg.Tenants.Enabled {
+		tenant = func(c *fiber.Ctx) string {
+			return tenancy.FromContext(c.UserContext())
+		}
+	}
+	var (
+		primary func(*fiber.Ctx) bool
+		settle  time.Duration
+	)
+	if len(cfg.Postgres.Replicas.Hosts) > 0 {
+		// Responses read from replicas are cached once replicas caught up with
+		// the write, which sticky clients wait for too
+		primary = func(c *fiber.Ctx) bool {
+			return postgres.Primary(c.UserContext())
+		}
+		settle = cfg.Postgres.Replicas.Sticky.TTL
+	}
+	return httpcache.New(&httpcache.Config{
+		Storage:      storage,
+		Routes:       routes,
+		TTL:          cfg.Cache.TTL,
+		CacheControl: true,
+		Methods:      cfg.Cache.Policy.Methods,
+		Include:      prefixed(cfg.Cache.Policy.Include),
+		// Admin responses are never cached
+		Exclude:   append(prefixed(cfg.Cache.Policy.Exclude), cfg.HTTP.Prefix+cfg.Admin.Path),
+		Vary:      cfg.Cache.Policy.Vary,
+		Principal: principal,
+		Tenant:    tenant,
+		Primary:   primary,
+		Settle:    settle,
+	}, logger)
 }
 
+const swaggerPath = "/swagger"
+
 func setupSwagger(f *fiber.App, cfg *config.AppCfg) {
+	setupSwaggerInfo(cfg)
+	sr := f.Group(cfg.HTTP.Prefix + swaggerPath)
+	sr.Get("*", swagger.HandlerDefault)
+}
+
+func setupSwaggerInfo(cfg *config.AppCfg) {
 	swdocs.SwaggerInfo.Host = cfg.Swagger.Host
 	swdocs.SwaggerInfo.BasePath = cfg.Swagger.BasePath
-	sr := f.Group(cfg.HTTP.Prefix + "/swagger")
-	sr.Get("*", swagger.HandlerDefault)
 }
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"goapptemplate/config"
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/publisher"
+	"goapptemplate/pkg/tenancy"
+	"goapptemplate/pkg/tlsconfig"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+
+	redactedValue = "[REDACTED]"
+)
+
+// ValidateConfig reports configuration the service would fail to start with,
+// external services availability is not checked.
+func ValidateConfig(cfg *config.AppCfg) error {
+	_, _, err := parseLevels(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = logger.NewFormatter(cfg.Logger.Format.Type, cfg.Logger.Format.Pretty, cfg.Logger.Format.Fields)
+	if err != nil {
+		return errors.Wrap(err, "invalid logger format")
+	}
+	switch cfg.Logger.Sink.Type {
+	case logger.SinkNone, "", logger.SinkFile, logger.SinkHTTP:
+	default:
+		return errors.Errorf("unknown error sink type [%s]", cfg.Logger.Sink.Type)
+	}
+	if cfg.Postgres.Tx.Retries < 0 || cfg.Postgres.Tx.Backoff < 0 {
+		return errors.Errorf("invalid postgres transaction retries [%d] or backoff [%s]", cfg.Postgres.Tx.Retries, cfg.Postgres.Tx.Backoff)
+	}
+	_, err = cfg.Postgres.ReplicaConfigStrings()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Postgres.Replicas.Hosts) > 0 && (cfg.Postgres.Replicas.MaxLag < 0 || cfg.Postgres.Replicas.Sticky.TTL < 0) {
+		return errors.Errorf("invalid postgres replicas max lag [%s] or sticky TTL [%s]", cfg.Postgres.Replicas.MaxLag, cfg.Postgres.Replicas.Sticky.TTL)
+	}
+	if cfg.Migrations.Timeout <= 0 {
+		return errors.Errorf("invalid migrations timeout [%s]", cfg.Migrations.Timeout)
+	}
+	if !cfg.Migrations.Auto && cfg.Migrations.Wait && cfg.Migrations.Interval <= 0 {
+		return errors.Errorf("invalid migrations interval [%s]", cfg.Migrations.Interval)
+	}
+	switch cfg.Migrations.Verify {
+	case migrationsVerifyNone, migrationsVerifyWarn, migrationsVerifyFail:
+	default:
+		return errors.Errorf("unknown migrations verify mode [%s]", cfg.Migrations.Verify)
+	}
+	if cfg.Outbox.Enabled && (cfg.Outbox.Interval <= 0 || cfg.Outbox.Batch <= 0 || cfg.Outbox.Timeout <= 0) {
+		return errors.Errorf("invalid outbox interval [%s], batch [%d] or timeout [%s]", cfg.Outbox.Interval, cfg.Outbox.Batch, cfg.Outbox.Timeout)
+	}
+	if cfg.Outbox.Enabled && cfg.Outbox.BatchTimeout < cfg.Outbox.Timeout {
+		return errors.Errorf("outbox batch timeout [%s] is shorter than timeout [%s]", cfg.Outbox.BatchTimeout, cfg.Outbox.Timeout)
+	}
+	if cfg.Outbox.Enabled && (cfg.Outbox.Backoff <= 0 || cfg.Outbox.MaxBackoff < cfg.Outbox.Backoff) {
+		return errors.Errorf("invalid outbox backoff [%s] or max backoff [%s]", cfg.Outbox.Backoff, cfg.Outbox.MaxBackoff)
+	}
+	if cfg.Outbox.Enabled {
+		switch cfg.Outbox.Publisher.Type {
+		case publisher.TypeLog, publisher.TypeStdout, publisher.TypeWebhook, publisher.TypeNATS, publisher.TypeKafka, publisher.TypeAMQP:
+		default:
+			return errors.Errorf("unknown outbox publisher type [%s]", cfg.Outbox.Publisher.Type)
+		}
+		_, err = publisher.NewEncoder(cfg.Outbox.Publisher.Mode, cfg.Outbox.Publisher.Source, cfg.Outbox.Publisher.TypePrefix)
+		if err != nil {
+			return errors.Wrap(err, "invalid outbox publisher")
+		}
+	}
+	switch cfg.Tenants.Mode {
+	case tenantModeSchema, tenantModeRow:
+	default:
+		return errors.Errorf("unknown tenants mode [%s]", cfg.Tenants.Mode)
+	}
+	if cfg.Tenants.Enabled {
+		_, err = tenancy.New(tenancyConfig(cfg, nil), discardLogger())
+		if err != nil {
+			return errors.Wrap(err, "invalid tenants")
+		}
+	}
+	if cfg.Tenants.Concurrency <= 0 {
+		return errors.Errorf("invalid tenants concurrency [%d]", cfg.Tenants.Concurrency)
+	}
+	switch cfg.Cache.Driver {
+	case cacheDriverNone, cacheDriverMemory, cacheDriverRedis:
+	default:
+		return errors.Errorf("unknown cache driver [%s]", cfg.Cache.Driver)
+	}
+	switch cfg.Cache.Policy.Scope {
+	case cachePolicyScopeShared, cachePolicyScopePrincipal:
+	default:
+		return errors.Errorf("unknown cache policy scope [%s]", cfg.Cache.Policy.Scope)
+	}
+	if cfg.Cache.Repo.Enabled && cfg.Cache.Repo.Timeout <= 0 {
+		return errors.Errorf("invalid cache repo timeout [%s]", cfg.Cache.Repo.Timeout)
+	}
+	if cfg.RateLimit.Enabled {
+		_, err = rateLimitConfig(cfg)
+		if err != nil {
+			return errors.Wrap(err, "invalid rate limit")
+		}
+		if cfg.RateLimit.GCInterval <= 0 {
+			return errors.Errorf("invalid rate limit gc interval [%s]", cfg.RateLimit.GCInterval)
+		}
+	}
+	_, err = newSecurity(cfg)
+	if err != nil {
+		return errors.Wrap(err, "invalid security")
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		reloader, err := tlsconfig.NewReloader(cfg.TLS.Cert.Filepath, cfg.TLS.Key.Filepath, 0, discardLogger())
+		if err != nil {
+			return errors.Wrap(err, "invalid tls certificate")
+		}
+		tlsConfig, err = tlsconfig.New(&tlsconfig.Config{
+			MinVersion:   cfg.TLS.MinVersion,
+			CipherSuites: cfg.TLS.CipherSuites,
+			ClientCAFile: cfg.TLS.ClientCA.Filepath,
+			ClientAuth:   cfg.TLS.ClientAuth,
+		}, reloader)
+		if err != nil {
+			return errors.Wrap(err, "invalid tls")
+		}
+	}
+	_, err = newListenerConfigs(cfg, tlsConfig)
+	if err != nil {
+		return errors.Wrap(err, "invalid listeners")
+	}
+	return nil
+}
+
+func discardLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// PrintConfig writes effective configuration in format, values of fields
+// matching logger redact fields are masked.
+func PrintConfig(w io.Writer, cfg *config.AppCfg, format string) error {
+	// Principals may be API keys
+	c := *cfg
+	c.RateLimit.Principals = make(map[string]string, len(cfg.RateLimit.Principals))
+	i := 0
+	for _, l := range cfg.RateLimit.Principals {
+		i++
+		c.RateLimit.Principals[fmt.Sprintf("%s#%d", redactedValue, i)] = l
+	}
+	// Broker URLs may carry credentials
+	c.Outbox.Publisher.NATS.URL = redactURLs(cfg.Outbox.Publisher.NATS.URL)
+	c.Outbox.Publisher.AMQP.URL = redactURLs(cfg.Outbox.Publisher.AMQP.URL)
+	cfg = &c
+	switch format {
+	case FormatYAML:
+		m := configMap(reflect.ValueOf(cfg), "yaml", cfg.Logger.Redact.Fields)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(m)
+	case FormatJSON:
+		m := configMap(reflect.ValueOf(cfg), "json", cfg.Logger.Redact.Fields)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(m)
+	default:
+		return errors.Errorf("unknown format [%s]", format)
+	}
+}
+
+// configMap converts configuration to values keyed by tag names, durations are
+// formatted the way they are configured.
+func configMap(v reflect.Value, tag string, redact []string) interface{} {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		m := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if redacted(name, redact) && !v.Field(i).IsZero() {
+				m[name] = redactedValue
+				continue
+			}
+			m[name] = configMap(v.Field(i), tag, redact)
+		}
+		return m
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			// Keys may be names of secrets too, e.g. webhook Authorization header
+			key := iter.Key().String()
+			if redacted(key, redact) && !iter.Value().IsZero() {
+				m[key] = redactedValue
+				continue
+			}
+			m[key] = configMap(iter.Value(), tag, redact)
+		}
+		return m
+	case reflect.Slice:
+		s := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, configMap(v.Index(i), tag, redact))
+		}
+		return s
+	default:
+		return v.Interface()
+	}
+}
+
+// redactURLs masks passwords of comma separated URLs.
+func redactURLs(urls string) string {
+	s := strings.Split(urls, ",")
+	for i, raw := range s {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err == nil {
+			s[i] = u.Redacted()
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+func redacted(name string, fields []string) bool {
+	name = strings.ToLower(name)
+	for _, f := range fields {
+		if strings.Contains(name, strings.ToLower(f)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"goapptemplate/config"
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/tenancy"
+
+	httpController "goapptemplate/internal/controller/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// newLogger creates application logger, returned func flushes and closes error sink.
+func newLogger(cfg *config.AppCfg) (*logrus.Logger, *logger.Levels, func() error) {
+	l := logrus.New()
+	lvl, layers, err := parseLevels(cfg)
+	if err != nil {
+		l.WithError(err).Fatal("cannot parse logger levels")
+	}
+	levels := logger.NewLevels(l, lvl, layers)
+	f, err := logger.NewFormatter(
+		cfg.Logger.Format.Type,
+		cfg.Logger.Format.Pretty,
+		cfg.Logger.Format.Fields,
+	)
+	if err != nil {
+		l.WithError(err).Fatalf("cannot create logger formatter [%s]", cfg.Logger.Format.Type)
+	}
+	f.Filters = append(f.Filters, levels.Enabled)
+	if cfg.Logger.RateLimit.Burst > 0 {
+		rl := logger.NewRateLimiter(cfg.Logger.RateLimit.Window, cfg.Logger.RateLimit.Burst)
+		f.Filters = append(f.Filters, rl.Allow)
+	}
+	f.Processors = append(
+		f.Processors,
+		logger.ErrorStack,
+		logger.NewRedactor(cfg.Logger.Redact.Fields, cfg.Logger.Redact.Mask).Redact,
+	)
+	if cfg.Logger.File.Path != "" {
+		w, err := logger.NewRotatingFile(
+			cfg.Logger.File.Path,
+			cfg.Logger.File.Name,
+			cfg.Logger.File.MaxAge,
+			cfg.Logger.File.RotationTime,
+		)
+		if err != nil {
+			l.WithError(err).Fatal("cannot create logger file output")
+		}
+		l.SetOutput(w)
+	}
+	// Sink is created last, fatal errors above exit before it buffers anything
+	closeSink := func() error { return nil }
+	sink, err := newErrorSink(cfg)
+	if err != nil {
+		l.WithError(err).Fatalf("cannot create error sink [%s]", cfg.Logger.Sink.Type)
+	}
+	if sink != nil {
+		sinkLvl, err := logrus.ParseLevel(cfg.Logger.Sink.Level)
+		if err != nil {
+			l.WithError(err).Fatalf("cannot parse error sink level [%s]", cfg.Logger.Sink.Level)
+		}
+		as := logger.NewAsyncSink(sink, sinkLvl, cfg.Logger.Sink.Buffer, cfg.Logger.Sink.Timeout)
+		f.Processors = append(f.Processors, as.Process)
+		closeSink = as.Close
+	}
+	l.Formatter = f
+	l.SetReportCaller(cfg.Logger.Format.Caller)
+	return l, levels, closeSink
+}
+
+func newErrorSink(cfg *config.AppCfg) (logger.Sink, error) {
+	switch cfg.Logger.Sink.Type {
+	case logger.SinkNone, "":
+		return nil, nil
+	case logger.SinkFile:
+		return logger.NewFileSink(cfg.Logger.Sink.Filepath)
+	case logger.SinkHTTP:
+		if cfg.Logger.Sink.URL == "" {
+			return nil, errors.New("error sink url is required")
+		}
+		return logger.NewHTTPSink(cfg.Logger.Sink.URL), nil
+	default:
+		return nil, errors.New("unknown error sink type")
+	}
+}
+
+func parseLevels(cfg *config.AppCfg) (logrus.Level, map[string]logrus.Level, error) {
+	lvl, err := logrus.ParseLevel(cfg.Logger.Level)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "cannot parse logrus level [%s]", cfg.Logger.Level)
+	}
+	layers, err := logger.ParseLayers(cfg.Logger.Layers)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "cannot parse logrus layer levels")
+	}
+	return lvl, layers, nil
+}
+
+// reloadLevels re-reads configuration and applies logger levels from it.
+func reloadLevels(cfg *config.AppCfg, levels *logger.Levels) error {
+	c, err := cfg.Reload()
+	if err != nil {
+		return errors.Wrap(err, "cannot reload config")
+	}
+	lvl, layers, err := parseLevels(c)
+	if err != nil {
+		return err
+	}
+	levels.Set(lvl, layers)
+	return nil
+}
+
+// accessLogLocals returns request locals logged by access log keyed by fields, the
+// same fields carry them in request scoped entries.
+func accessLogLocals() map[string]string {
+	return map[string]string{
+		logger.FieldRequestID: httpController.LocalsRequestID,
+		logger.FieldTenant:    tenancy.LocalsTenant,
+	}
+}
@@ -2,29 +2,101 @@ package app
 
 import (
 	"context"
+	"embed"
 	"goapptemplate"
 	"goapptemplate/config"
 	"goapptemplate/internal/domain"
 	"goapptemplate/pkg/migrator"
 
+	gomigrate "github.com/golang-migrate/migrate/v4"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
-func migrate(cfg *config.AppCfg) error {
-	mu, err := migrator.NewPostgresMigrator(
-		cfg.Postgres.ConfigURL(),
-		domain.SchemaApp,
-		goapptemplate.MigrationsApp,
-		"migrations/app",
-	)
+// NewMigrator creates migrator of application schema.
+func NewMigrator(cfg *config.AppCfg) (*migrator.PostgresMigrator, error) {
+	return newMigrator(cfg, domain.SchemaApp, goapptemplate.MigrationsApp, "migrations/app")
+}
+
+func newMigrator(cfg *config.AppCfg, schema string, migrations embed.FS, path string) (*migrator.PostgresMigrator, error) {
+	mu, err := migrator.NewPostgresMigrator(cfg.Postgres.ConfigURL(), schema, migrations, path)
 	if err != nil {
-		return errors.Wrap(err, "cannot create postgres migrator")
+		return nil, errors.Wrap(err, "cannot create postgres migrator")
+	}
+	return mu, nil
+}
+
+// Checksum verification modes of applied migrations
+const (
+	migrationsVerifyNone = "none"
+	migrationsVerifyWarn = "warn"
+	migrationsVerifyFail = "fail"
+)
+
+// migrate applies migrations when they are automatic, instances are serialized by
+// migrator lock. Otherwise it waits until migrations are applied by another instance
+// (e.g. migrate command run as a job) if waiting is enabled. Checksums of applied
+// migrations are verified afterwards.
+func migrate(cfg *config.AppCfg, logger *logrus.Logger) error {
+	if !cfg.Migrations.Auto && !cfg.Migrations.Wait && cfg.Migrations.Verify == migrationsVerifyNone {
+		return nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.Timeout)
+	mu, err := NewMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer mu.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Migrations.Timeout)
 	defer cancel()
-	err = mu.Up(ctx)
+	switch {
+	case cfg.Migrations.Auto:
+		err = mu.Up(ctx)
+		if errors.Is(err, gomigrate.ErrNoChange) {
+			logger.Info("Schema is up to date")
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "cannot migrate up")
+		}
+		logger.Info("Successfully applied migrations")
+	case cfg.Migrations.Wait:
+		versions, err := mu.Versions()
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			break
+		}
+		version := versions[len(versions)-1]
+		logger.WithField("version", version).Info("Waiting for migrations")
+		err = mu.WaitVersion(ctx, version, cfg.Migrations.Interval)
+		if err != nil {
+			return errors.Wrap(err, "cannot wait for migrations")
+		}
+		logger.WithField("version", version).Info("Schema is migrated")
+	}
+	return verifyMigrations(ctx, cfg, mu, logger)
+}
+
+// verifyMigrations reports applied migrations which files are changed since.
+func verifyMigrations(ctx context.Context, cfg *config.AppCfg, mu *migrator.PostgresMigrator, logger *logrus.Logger) error {
+	if cfg.Migrations.Verify == migrationsVerifyNone {
+		return nil
+	}
+	mismatches, err := mu.Verify(ctx)
 	if err != nil {
-		return errors.Wrap(err, "cannot migrate up")
+		return errors.Wrap(err, "cannot verify migrations")
+	}
+	for _, m := range mismatches {
+		logger.WithFields(logrus.Fields{
+			"version":    m.Version,
+			"identifier": m.Identifier,
+			"applied":    m.Applied,
+			"source":     m.Source,
+		}).Warning("Applied migration is changed, add a new migration instead")
+	}
+	if len(mismatches) > 0 && cfg.Migrations.Verify == migrationsVerifyFail {
+		return errors.Errorf("[%d] applied migrations are changed", len(mismatches))
 	}
 	return nil
 }
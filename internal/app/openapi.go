@@ -0,0 +1,44 @@
+package app
+
+import (
+	"goapptemplate/config"
+	"io"
+
+	swdocs "goapptemplate/docs"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// WriteOpenAPI writes OpenAPI specification served by swagger UI in format.
+func WriteOpenAPI(w io.Writer, cfg *config.AppCfg, format string) error {
+	setupSwaggerInfo(cfg)
+	doc := swdocs.SwaggerInfo.ReadDoc()
+	switch format {
+	case FormatJSON:
+		_, err := io.WriteString(w, doc+"\n")
+		return err
+	case FormatYAML:
+		// JSON is YAML, decoding into node keeps keys order
+		var node yaml.Node
+		err := yaml.Unmarshal([]byte(doc), &node)
+		if err != nil {
+			return errors.Wrap(err, "cannot decode specification")
+		}
+		blockStyle(&node)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(&node)
+	default:
+		return errors.Errorf("unknown format [%s]", format)
+	}
+}
+
+// blockStyle makes JSON flow style nodes rendered as YAML blocks.
+func blockStyle(n *yaml.Node) {
+	n.Style &^= yaml.FlowStyle
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"goapptemplate/config"
+	"goapptemplate/internal/domain"
+	"goapptemplate/internal/usecase"
+	"goapptemplate/pkg/outbox"
+	"goapptemplate/pkg/postgres"
+	"goapptemplate/pkg/publisher"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// newOutboxRelay creates relay of events written to outbox of application schema,
+// with schema per tenant outboxes of tenants registered in tenants are relayed too.
+func newOutboxRelay(cfg *config.AppCfg, db *postgres.PostgresDB, publisher outbox.Publisher, tenants usecase.TenantsRepo, logger *logrus.Logger) *outbox.Relay {
+	rc := &outbox.RelayConfig{
+		Interval:     cfg.Outbox.Interval,
+		Batch:        cfg.Outbox.Batch,
+		Timeout:      cfg.Outbox.Timeout,
+		BatchTimeout: cfg.Outbox.BatchTimeout,
+		Backoff:      cfg.Outbox.Backoff,
+		MaxBackoff:   cfg.Outbox.MaxBackoff,
+		Retention:    cfg.Outbox.Retention,
+	}
+	if tenants != nil && cfg.Tenants.Mode == tenantModeSchema {
+		rc.Schemas = func(ctx context.Context) ([]string, error) {
+			all, err := tenants.RetrieveAll(ctx)
+			if err != nil {
+				return nil, err
+			}
+			schemas := []string{domain.SchemaApp}
+			for _, t := range all {
+				if t.Schema != "" {
+					schemas = append(schemas, t.Schema)
+				}
+			}
+			return schemas, nil
+		}
+	}
+	return outbox.NewRelay(db, publisher, rc, logger)
+}
+
+// newPublisher creates publisher of outbox events of configured type, events are
+// encoded as CloudEvents of configured mode.
+func newPublisher(cfg *config.AppCfg, logger *logrus.Logger) (publisher.Publisher, error) {
+	pc := &cfg.Outbox.Publisher
+	enc, err := publisher.NewEncoder(pc.Mode, pc.Source, pc.TypePrefix)
+	if err != nil {
+		return nil, err
+	}
+	switch pc.Type {
+	case publisher.TypeLog:
+		return publisher.NewLog(enc, logger), nil
+	case publisher.TypeStdout:
+		return publisher.NewWriter(enc, os.Stdout), nil
+	case publisher.TypeWebhook:
+		return publisher.NewWebhook(enc, &publisher.WebhookConfig{
+			URL:     pc.Webhook.URL,
+			Headers: pc.Webhook.Headers,
+		})
+	case publisher.TypeNATS:
+		return publisher.NewNATS(enc, &publisher.NATSConfig{
+			URL:       pc.NATS.URL,
+			Subject:   pc.NATS.Subject,
+			JetStream: pc.NATS.JetStream,
+		}, logger)
+	case publisher.TypeKafka:
+		return publisher.NewKafka(enc, &publisher.KafkaConfig{
+			Brokers: pc.Kafka.Brokers,
+			Topic:   pc.Kafka.Topic,
+		})
+	case publisher.TypeAMQP:
+		return publisher.NewAMQP(enc, &publisher.AMQPConfig{
+			URL:        pc.AMQP.URL,
+			Exchange:   pc.AMQP.Exchange,
+			RoutingKey: pc.AMQP.RoutingKey,
+		}, logger)
+	default:
+		return nil, errors.New("unknown outbox publisher type")
+	}
+}
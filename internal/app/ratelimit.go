@@ -0,0 +1,108 @@
+package app
+
+import (
+	"goapptemplate/config"
+	"goapptemplate/pkg/ratelimit"
+	"io"
+
+	httpController "goapptemplate/internal/controller/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/redis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	headerAPIKey = "X-API-Key"
+
+	rateLimitKeyPrefix = "ratelimit:"
+)
+
+// newRateLimiter creates rate limiting middleware, limits are shared by instances
+// when storage is Redis and kept in memory otherwise. Returned closer stops
+// in-memory limiter.
+func newRateLimiter(cfg *config.AppCfg, storage fiber.Storage, logger *logrus.Logger) (*ratelimit.Middleware, io.Closer, error) {
+	rc, err := rateLimitConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	memory, err := ratelimit.NewMemoryLimiter(cfg.RateLimit.Algorithm, cfg.RateLimit.GCInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc.Limiter = memory
+	if rs, ok := storage.(*redis.Storage); ok {
+		rl, err := ratelimit.NewRedisLimiter(rs.Conn(), cfg.RateLimit.Algorithm, rateLimitKeyPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		rc.Limiter = ratelimit.NewFallbackLimiter(rl, memory, cfg.RateLimit.Cooldown, logger)
+	}
+	return ratelimit.New(rc, logger), memory, nil
+}
+
+// rateLimitConfig parses configured limits, limiter is left unset.
+func rateLimitConfig(cfg *config.AppCfg) (*ratelimit.Config, error) {
+	switch cfg.RateLimit.Algorithm {
+	case ratelimit.AlgorithmTokenBucket, ratelimit.AlgorithmSlidingWindow:
+	default:
+		return nil, errors.Wrapf(ratelimit.ErrAlgorithm, "[%s]", cfg.RateLimit.Algorithm)
+	}
+	limit, err := parseLimit(cfg, cfg.RateLimit.Limit)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]ratelimit.Group, 0, len(cfg.RateLimit.Groups))
+	for path, l := range cfg.RateLimit.Groups {
+		gl, err := parseLimit(cfg, l)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid group [%s] limit", path)
+		}
+		groups = append(groups, ratelimit.Group{
+			Path:  cfg.HTTP.Prefix + path,
+			Limit: gl,
+		})
+	}
+	principals := make(map[string]ratelimit.Limit, len(cfg.RateLimit.Principals))
+	for principal, l := range cfg.RateLimit.Principals {
+		pl, err := parseLimit(cfg, l)
+		if err != nil {
+			// Principal is not a part of the error, it may be an API key
+			return nil, errors.Wrap(err, "invalid principal limit")
+		}
+		principals[principal] = pl
+	}
+	return &ratelimit.Config{
+		Limit:      limit,
+		Groups:     groups,
+		Key:        rateLimitKey(principals),
+		Principals: principals,
+	}, nil
+}
+
+// rateLimitKey returns function identifying client by verified principal, API key
+// with configured limit or IP. Unverified headers are not used, otherwise clients
+// could escape limits sending a new value with every request.
+func rateLimitKey(principals map[string]ratelimit.Limit) func(c *fiber.Ctx) string {
+	return func(c *fiber.Ctx) string {
+		if p := httpController.VerifiedPrincipal(c); p != "" {
+			return p
+		}
+		if k := c.Get(headerAPIKey); k != "" {
+			if _, ok := principals[k]; ok {
+				return k
+			}
+		}
+		return c.IP()
+	}
+}
+
+func parseLimit(cfg *config.AppCfg, s string) (ratelimit.Limit, error) {
+	l, err := ratelimit.ParseLimit(s)
+	if err != nil {
+		return l, err
+	}
+	l.Burst = cfg.RateLimit.Burst
+	return l, nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"goapptemplate/pkg/ratelimit"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	httpController "goapptemplate/internal/controller/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	key := rateLimitKey(map[string]ratelimit.Limit{"known-key": {}})
+	tests := []struct {
+		name      string
+		principal string
+		headers   map[string]string
+		want      string
+	}{
+		{
+			name: "ip",
+			want: "0.0.0.0",
+		},
+		{
+			name:      "verified principal",
+			principal: "CN=client",
+			headers:   map[string]string{headerAPIKey: "known-key"},
+			want:      "CN=client",
+		},
+		{
+			name:    "configured api key",
+			headers: map[string]string{headerAPIKey: "known-key"},
+			want:    "known-key",
+		},
+		{
+			name:    "unknown api key",
+			headers: map[string]string{headerAPIKey: "random"},
+			want:    "0.0.0.0",
+		},
+		{
+			name:    "unverified authorization",
+			headers: map[string]string{fiber.HeaderAuthorization: "Bearer random"},
+			want:    "0.0.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fiber.New()
+			f.Get("/", func(c *fiber.Ctx) error {
+				if tt.principal != "" {
+					c.Locals(httpController.LocalsPrincipal, tt.principal)
+				}
+				return c.SendString(key(c))
+			})
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			res, err := f.Test(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			b, _ := io.ReadAll(res.Body)
+			if got := string(b); got != tt.want {
+				t.Fatalf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
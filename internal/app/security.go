@@ -0,0 +1,110 @@
+package app
+
+import (
+	"goapptemplate/config"
+	"goapptemplate/pkg/security"
+)
+
+// newSecurity creates CORS and security headers middleware, swagger UI gets a looser
+// content security policy unless its group is configured explicitly.
+func newSecurity(cfg *config.AppCfg) (*security.Middleware, error) {
+	sc := &security.Config{
+		CORS:    corsPolicy(&cfg.Security.CORS),
+		Headers: securityHeaders(&cfg.Security.Headers),
+		Groups:  make([]security.Group, 0, len(cfg.Security.Groups)+1),
+	}
+	for path, g := range cfg.Security.Groups {
+		group := security.Group{
+			Path:    cfg.HTTP.Prefix + path,
+			CORS:    sc.CORS,
+			Headers: sc.Headers,
+		}
+		if g.CORS != nil {
+			mergeCORS(&group.CORS, g.CORS)
+		}
+		if g.Headers != nil {
+			mergeHeaders(&group.Headers, g.Headers)
+		}
+		sc.Groups = append(sc.Groups, group)
+	}
+	if _, ok := cfg.Security.Groups[swaggerPath]; !ok {
+		group := security.Group{
+			Path:    cfg.HTTP.Prefix + swaggerPath,
+			CORS:    sc.CORS,
+			Headers: sc.Headers,
+		}
+		group.Headers.ContentSecurityPolicy = cfg.Swagger.ContentSecurityPolicy
+		sc.Groups = append(sc.Groups, group)
+	}
+	return security.New(sc)
+}
+
+func corsPolicy(c *config.CORS) security.CORS {
+	return security.CORS{
+		AllowOrigins:     c.AllowOrigins,
+		AllowMethods:     c.AllowMethods,
+		AllowHeaders:     c.AllowHeaders,
+		ExposeHeaders:    c.ExposeHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+func securityHeaders(h *config.SecurityHeaders) security.Headers {
+	return security.Headers{
+		ContentSecurityPolicy: h.ContentSecurityPolicy,
+		CSPReportOnly:         h.CSPReportOnly,
+		HSTSMaxAge:            h.HSTS.MaxAge,
+		HSTSIncludeSubdomains: h.HSTS.IncludeSubdomains,
+		HSTSPreload:           h.HSTS.Preload,
+		FrameOptions:          h.FrameOptions,
+		ReferrerPolicy:        h.ReferrerPolicy,
+	}
+}
+
+// mergeCORS overrides non-empty fields of dst.
+func mergeCORS(dst *security.CORS, src *config.CORS) {
+	if len(src.AllowOrigins) > 0 {
+		dst.AllowOrigins = src.AllowOrigins
+	}
+	if len(src.AllowMethods) > 0 {
+		dst.AllowMethods = src.AllowMethods
+	}
+	if len(src.AllowHeaders) > 0 {
+		dst.AllowHeaders = src.AllowHeaders
+	}
+	if len(src.ExposeHeaders) > 0 {
+		dst.ExposeHeaders = src.ExposeHeaders
+	}
+	if src.AllowCredentials {
+		dst.AllowCredentials = true
+	}
+	if src.MaxAge != 0 {
+		dst.MaxAge = src.MaxAge
+	}
+}
+
+// mergeHeaders overrides non-empty fields of dst.
+func mergeHeaders(dst *security.Headers, src *config.SecurityHeaders) {
+	if src.ContentSecurityPolicy != "" {
+		dst.ContentSecurityPolicy = src.ContentSecurityPolicy
+	}
+	if src.CSPReportOnly {
+		dst.CSPReportOnly = true
+	}
+	if src.HSTS.MaxAge != 0 {
+		dst.HSTSMaxAge = src.HSTS.MaxAge
+	}
+	if src.HSTS.IncludeSubdomains {
+		dst.HSTSIncludeSubdomains = true
+	}
+	if src.HSTS.Preload {
+		dst.HSTSPreload = true
+	}
+	if src.FrameOptions != "" {
+		dst.FrameOptions = src.FrameOptions
+	}
+	if src.ReferrerPolicy != "" {
+		dst.ReferrerPolicy = src.ReferrerPolicy
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"goapptemplate/config"
+	"goapptemplate/internal/domain"
+	"goapptemplate/internal/usecase"
+	"goapptemplate/internal/usecase/repo"
+	"goapptemplate/pkg/postgres"
+
+	"github.com/pkg/errors"
+)
+
+var seedBooks = []domain.Book{
+	{Name: "The Go Programming Language", Description: "Alan A. A. Donovan, Brian W. Kernighan"},
+	{Name: "Concurrency in Go", Description: "Katherine Cox-Buday"},
+	{Name: "Designing Data-Intensive Applications", Description: "Martin Kleppmann"},
+	{Name: "PostgreSQL: Up and Running", Description: "Regina Obe, Leo Hsu"},
+	{Name: "Release It!", Description: "Michael T. Nygard"},
+}
+
+// Seed stores sample books through usecase when there are no books yet,
+// force stores them regardless. Returns the number of stored books.
+func Seed(ctx context.Context, cfg *config.AppCfg, force bool) (int, error) {
+	logger, _, closeLogger := newLogger(cfg)
+	defer closeLogger()
+	db, err := postgres.NewPostgresDB(
+		ctx,
+		cfg.Postgres.ConfigString(
+			fmt.Sprintf(
+				"search_path=%s",
+				domain.SchemaApp,
+			),
+		),
+		nil,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot create postgres db")
+	}
+	defer db.Close()
+	db.Retry = postgresRetry(cfg)
+	books := usecase.NewBooks(repo.NewBooksPostgresRepo(db, logger), logger)
+	if !force {
+		page, err := books.List(ctx, &domain.BookFilters{Filters: domain.Filters{Limit: 1}})
+		if err != nil {
+			return 0, errors.Wrap(err, "cannot view books")
+		}
+		if page.Total > 0 {
+			return 0, nil
+		}
+	}
+	for i := range seedBooks {
+		b := seedBooks[i]
+		_, err = books.New(ctx, &b)
+		if err != nil {
+			return i, errors.Wrapf(err, "cannot store book [%s]", b.Name)
+		}
+	}
+	return len(seedBooks), nil
+}
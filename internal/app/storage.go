@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"goapptemplate/config"
+	"goapptemplate/pkg/lru"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	cacheDriverNone   = "none"
+	cacheDriverMemory = "memory"
+	cacheDriverRedis  = "redis"
+
+	cachePolicyScopeShared    = "shared"
+	cachePolicyScopePrincipal = "principal"
+)
+
+// newStorage creates storage shared by cache and other storage consumers,
+// nil storage is returned for "none" driver.
+func newStorage(cfg *config.AppCfg) (storage fiber.Storage, err error) {
+	switch cfg.Cache.Driver {
+	case cacheDriverNone:
+		return nil, nil
+	case cacheDriverMemory:
+		return lru.New(cfg.Cache.Memory.MaxEntries, cfg.Cache.Memory.GCInterval), nil
+	case cacheDriverRedis:
+		// Redis storage panics when server is unreachable
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("cannot connect to redis [%s]: %v", cfg.Redis.Addr(), r)
+			}
+		}()
+		return redis.New(redis.Config{
+			Host:     cfg.Redis.Host,
+			Port:     int(cfg.Redis.Port),
+			Username: cfg.Redis.Username,
+			Password: cfg.Redis.Password,
+			Database: cfg.Redis.DB,
+		}), nil
+	default:
+		return nil, errors.Errorf("unknown cache driver [%s]", cfg.Cache.Driver)
+	}
+}
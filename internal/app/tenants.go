@@ -0,0 +1,359 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"goapptemplate"
+	"goapptemplate/config"
+	"goapptemplate/internal/domain"
+	"goapptemplate/internal/usecase"
+	"goapptemplate/internal/usecase/repo"
+	"goapptemplate/pkg/migrator"
+	"goapptemplate/pkg/postgres"
+	"goapptemplate/pkg/tenancy"
+	"sync"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Tenant isolation modes, a schema per tenant or rows of shared application
+// schema protected by row level security
+const (
+	tenantModeSchema = "schema"
+	tenantModeRow    = "row"
+)
+
+// Statuses of tenant schema migration
+const (
+	TenantMigrated = "migrated"
+	TenantUpToDate = "up to date"
+	TenantFailed   = "failed"
+	// TenantShared tenant shares application schema, which is migrated separately
+	TenantShared = "shared"
+)
+
+// TenantMigration is a result of migrating tenant schema, versions are -1 when
+// no migrations are applied.
+type TenantMigration struct {
+	Tenant string
+	Schema string
+	From   int
+	To     int
+	Status string
+	Err    error
+}
+
+// ListTenants returns registered tenants.
+func ListTenants(ctx context.Context, cfg *config.AppCfg) ([]*domain.Tenant, error) {
+	logger, _, closeLogger := newLogger(cfg)
+	defer closeLogger()
+	tenants, closeRegistry, err := newTenantsRegistry(ctx, cfg, true, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRegistry()
+	return tenants.RetrieveAll(ctx)
+}
+
+// ProvisionTenant creates tenant schema, applies application migrations to it and
+// registers tenant. In row mode tenant is only registered, its rows are stored in
+// application schema. domain.ErrTenantExists is returned for registered tenant.
+func ProvisionTenant(ctx context.Context, cfg *config.AppCfg, tenantID string) (*domain.Tenant, error) {
+	t := &domain.Tenant{ID: tenantID}
+	err := t.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Tenants.Mode != tenantModeRow {
+		t.Schema = domain.SchemaTenant(t.ID)
+	}
+	logger, _, closeLogger := newLogger(cfg)
+	defer closeLogger()
+	tenants, closeRegistry, err := newTenantsRegistry(ctx, cfg, true, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRegistry()
+	_, err = tenants.Retrieve(ctx, t.ID)
+	if err == nil {
+		return nil, domain.ErrTenantExists
+	}
+	if !errors.Is(err, domain.ErrTenantNotFound) {
+		return nil, err
+	}
+	tm := TenantMigration{To: -1}
+	if t.Schema != "" {
+		tm = migrateTenant(ctx, cfg, t)
+		if tm.Err != nil {
+			return nil, tm.Err
+		}
+	}
+	t, err = tenants.Store(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	logger.WithFields(logrus.Fields{
+		"tenant_id": t.ID,
+		"schema":    t.Schema,
+		"version":   tm.To,
+	}).Info("Provisioned tenant")
+	return t, nil
+}
+
+// DeprovisionTenant unregisters tenant and drops its schema with all data, rows of
+// tenant sharing application schema are deleted instead. Confirm must repeat tenant ID.
+func DeprovisionTenant(ctx context.Context, cfg *config.AppCfg, tenantID string, confirm string) error {
+	if confirm != tenantID {
+		return domain.ErrTenantConfirmation
+	}
+	logger, _, closeLogger := newLogger(cfg)
+	defer closeLogger()
+	tenants, closeRegistry, err := newTenantsRegistry(ctx, cfg, true, logger)
+	if err != nil {
+		return err
+	}
+	defer closeRegistry()
+	t, err := tenants.Retrieve(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	// Tenant is not resolved anymore before its schema is dropped
+	err = tenants.Remove(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if t.Schema == "" {
+		err = deleteTenantRows(ctx, cfg, t.ID)
+		if err != nil {
+			return errors.Wrapf(err, "cannot delete tenant [%s] rows", t.ID)
+		}
+	} else {
+		mu, err := NewMigrator(cfg)
+		if err != nil {
+			return err
+		}
+		defer mu.Close()
+		err = mu.DropSchema(ctx, t.Schema)
+		if err != nil {
+			return errors.Wrapf(err, "cannot drop tenant [%s] schema", t.ID)
+		}
+	}
+	logger.WithFields(logrus.Fields{
+		"tenant_id": t.ID,
+		"schema":    t.Schema,
+	}).Warning("Deprovisioned tenant")
+	return nil
+}
+
+// MigrateTenants applies pending migrations to schemas of all registered tenants,
+// at most concurrency tenants are migrated at once. Results are in tenants order.
+func MigrateTenants(ctx context.Context, cfg *config.AppCfg, concurrency int) ([]TenantMigration, error) {
+	if concurrency <= 0 {
+		concurrency = cfg.Tenants.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	logger, _, closeLogger := newLogger(cfg)
+	defer closeLogger()
+	tenants, closeRegistry, err := newTenantsRegistry(ctx, cfg, true, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRegistry()
+	all, err := tenants.RetrieveAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]TenantMigration, len(all))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, t := range all {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t *domain.Tenant) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if t.Schema == "" {
+				results[i] = TenantMigration{Tenant: t.ID, From: -1, To: -1, Status: TenantShared}
+				return
+			}
+			results[i] = migrateTenant(ctx, cfg, t)
+			entry := logger.WithFields(logrus.Fields{
+				"tenant_id": t.ID,
+				"schema":    t.Schema,
+				"from":      results[i].From,
+				"to":        results[i].To,
+			})
+			if results[i].Err != nil {
+				entry.WithError(results[i].Err).Error("cannot migrate tenant")
+				return
+			}
+			entry.Info("Migrated tenant")
+		}(i, t)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// migrateTenant applies application migrations to tenant schema, schema is
+// created when it does not exist.
+func migrateTenant(ctx context.Context, cfg *config.AppCfg, t *domain.Tenant) TenantMigration {
+	tm := TenantMigration{
+		Tenant: t.ID,
+		Schema: t.Schema,
+		From:   -1,
+		To:     -1,
+		Status: TenantFailed,
+	}
+	mu, err := newMigrator(cfg, t.Schema, goapptemplate.MigrationsApp, "migrations/app")
+	if err != nil {
+		tm.Err = err
+		return tm
+	}
+	defer mu.Close()
+	tm.From, tm.Err = schemaVersion(mu)
+	if tm.Err != nil {
+		return tm
+	}
+	err = mu.Up(ctx)
+	tm.To, _ = schemaVersion(mu)
+	switch {
+	case errors.Is(err, gomigrate.ErrNoChange):
+		tm.Status = TenantUpToDate
+	case err != nil:
+		tm.Err = err
+	default:
+		tm.Status = TenantMigrated
+	}
+	return tm
+}
+
+// deleteTenantRows deletes rows of tenant from application schema tables, row
+// level security limits deletion to tenant rows.
+func deleteTenantRows(ctx context.Context, cfg *config.AppCfg, tenantID string) error {
+	db, err := postgres.NewPostgresDB(
+		ctx,
+		cfg.Postgres.ConfigString(
+			fmt.Sprintf(
+				"search_path=%s",
+				domain.SchemaApp,
+			),
+		),
+		nil,
+	)
+	if err != nil {
+		return errors.Wrap(err, "cannot create postgres db")
+	}
+	defer db.Close()
+	return db.WithTx(postgres.WithTenantID(ctx, tenantID), pgx.TxOptions{}, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "delete from books where tenant_id = $1", tenantID)
+		if err != nil {
+			return errors.Wrap(err, "cannot delete books")
+		}
+		_, err = tx.Exec(ctx, "delete from outbox where tenant_id = $1", tenantID)
+		if err != nil {
+			return errors.Wrap(err, "cannot delete outbox events")
+		}
+		return nil
+	})
+}
+
+// checkRowLevelSecurity fails when connection role bypasses row level security,
+// tenants would not be isolated then.
+func checkRowLevelSecurity(ctx context.Context, db *postgres.PostgresDB) error {
+	var bypass bool
+	err := db.QueryRow(ctx, "select rolsuper or rolbypassrls from pg_roles where rolname = current_user").Scan(&bypass)
+	if err != nil {
+		return errors.Wrap(err, "cannot check role attributes")
+	}
+	if bypass {
+		return errors.New("postgres user is a superuser or has BYPASSRLS attribute, row level security does not apply to it")
+	}
+	return nil
+}
+
+func schemaVersion(mu *migrator.PostgresMigrator) (int, error) {
+	v, _, err := mu.Version()
+	if errors.Is(err, gomigrate.ErrNilVersion) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return int(v), nil
+}
+
+// newTenancy creates tenant resolution middleware validating tenants against
+// registry, which is returned too. Returned func closes registry connections.
+func newTenancy(ctx context.Context, cfg *config.AppCfg, logger *logrus.Logger) (*tenancy.Middleware, usecase.TenantsRepo, func(), error) {
+	tenants, closeRegistry, err := newTenantsRegistry(ctx, cfg, cfg.Migrations.Auto, logger)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tm, err := tenancy.New(tenancyConfig(cfg, tenants), logger)
+	if err != nil {
+		closeRegistry()
+		return nil, nil, nil, err
+	}
+	return tm, tenants, closeRegistry, nil
+}
+
+func tenancyConfig(cfg *config.AppCfg, tenants usecase.TenantsRepo) *tenancy.Config {
+	return &tenancy.Config{
+		Source:   cfg.Tenants.Source,
+		Header:   cfg.Tenants.Header,
+		Domain:   cfg.Tenants.Domain,
+		Claim:    cfg.Tenants.Claim,
+		Secret:   []byte(cfg.Tenants.Secret),
+		CacheTTL: cfg.Tenants.CacheTTL,
+		RowLevel: cfg.Tenants.Mode == tenantModeRow,
+		Lookup: func(ctx context.Context, tenantID string) (string, error) {
+			t := domain.Tenant{ID: tenantID}
+			if t.Validate() != nil {
+				return "", tenancy.ErrUnknownTenant
+			}
+			found, err := tenants.Retrieve(ctx, tenantID)
+			if errors.Is(err, domain.ErrTenantNotFound) {
+				return "", tenancy.ErrUnknownTenant
+			}
+			if err != nil {
+				return "", err
+			}
+			return found.Schema, nil
+		},
+	}
+}
+
+// newTenantsRegistry returns tenants repository, registry schema is migrated first
+// when migrateRegistry is set. Returned func closes repository connections.
+func newTenantsRegistry(ctx context.Context, cfg *config.AppCfg, migrateRegistry bool, logger *logrus.Logger) (usecase.TenantsRepo, func(), error) {
+	if migrateRegistry {
+		mu, err := newMigrator(cfg, domain.SchemaRegistry, goapptemplate.MigrationsRegistry, "migrations/registry")
+		if err != nil {
+			return nil, nil, err
+		}
+		err = mu.Up(ctx)
+		mu.Close()
+		if err != nil && !errors.Is(err, gomigrate.ErrNoChange) {
+			return nil, nil, errors.Wrap(err, "cannot migrate tenants registry")
+		}
+	}
+	db, err := postgres.NewPostgresDB(
+		ctx,
+		cfg.Postgres.ConfigString(
+			fmt.Sprintf(
+				"search_path=%s",
+				domain.SchemaRegistry,
+			),
+		),
+		nil,
+	)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot create postgres db")
+	}
+	return repo.NewTenantsPostgresRepo(db, logger), db.Close, nil
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/postgres"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type AdminHTTPController interface {
+	GetLogLevel() func(*fiber.Ctx) error
+	SetLogLevel() func(*fiber.Ctx) error
+	GetQueryStats() func(*fiber.Ctx) error
+	ResetQueryStats() func(*fiber.Ctx) error
+}
+
+type AdminHTTPControllerConfig struct {
+	BasePath string
+	// Principals are verified principals allowed to use admin endpoints, e.g.
+	// client certificate subjects, any client is allowed when empty.
+	Principals []string
+}
+
+type adminHTTPController struct {
+	f      *fiber.App
+	levels *logger.Levels
+	tracer postgres.QueryTracer
+	config *AdminHTTPControllerConfig
+	log    *logrus.Entry
+}
+
+type logLevels struct {
+	Level  string            `json:"level"`
+	Layers map[string]string `json:"layers"`
+}
+
+type logLevelUpdate struct {
+	Level string `json:"level"`
+	// Layer is optional, global level is changed when empty
+	Layer string `json:"layer"`
+	// Unset makes Layer fallback to global level
+	Unset bool `json:"unset"`
+}
+
+type queryStats struct {
+	Name   string `json:"name,omitempty"`
+	SQL    string `json:"sql"`
+	Count  int64  `json:"count"`
+	Errors int64  `json:"errors"`
+	Total  string `json:"total"`
+	Max    string `json:"max"`
+	P50    string `json:"p50"`
+	P95    string `json:"p95"`
+}
+
+// authorize rejects requests of principals not allowed by configuration. Only
+// principals verified by middleware are considered, Authorization header is not.
+func (hc *adminHTTPController) authorize() func(*fiber.Ctx) error {
+	allowed := make(map[string]struct{}, len(hc.config.Principals))
+	for _, p := range hc.config.Principals {
+		allowed[p] = struct{}{}
+	}
+	return func(c *fiber.Ctx) error {
+		if len(allowed) == 0 {
+			return c.Next()
+		}
+		p := VerifiedPrincipal(c)
+		if _, ok := allowed[p]; !ok || p == "" {
+			requestLogger(c, hc.log).WithField(logger.FieldUser, p).Warn("admin request is not authorized")
+			return c.Status(fiber.StatusForbidden).JSON(fiber.ErrForbidden)
+		}
+		return c.Next()
+	}
+}
+
+// GetLogLevel implements AdminHTTPController.
+func (hc *adminHTTPController) GetLogLevel() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		layers := make(map[string]string)
+		for k, v := range hc.levels.Layers() {
+			layers[k] = v.String()
+		}
+		return c.Status(fiber.StatusOK).JSON(&logLevels{
+			Level:  hc.levels.Level().String(),
+			Layers: layers,
+		})
+	}
+}
+
+// SetLogLevel implements AdminHTTPController.
+func (hc *adminHTTPController) SetLogLevel() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		u := new(logLevelUpdate)
+		err := c.BodyParser(u)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
+		}
+		if u.Unset {
+			if u.Layer == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, "layer is required to unset level"))
+			}
+			hc.levels.UnsetLayerLevel(u.Layer)
+			hc.log.WithField("target_layer", u.Layer).Info("log level unset")
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		lvl, err := logrus.ParseLevel(u.Level)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
+		}
+		if u.Layer == "" {
+			hc.levels.SetLevel(lvl)
+		} else {
+			hc.levels.SetLayerLevel(u.Layer, lvl)
+		}
+		hc.log.WithFields(logrus.Fields{
+			"target_layer": u.Layer,
+			"log_level":    lvl.String(),
+		}).Info("log level changed")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// GetQueryStats implements AdminHTTPController.
+func (hc *adminHTTPController) GetQueryStats() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		stats := hc.tracer.Stats()
+		res := make([]*queryStats, 0, len(stats))
+		for _, s := range stats {
+			res = append(res, &queryStats{
+				Name:   s.Name,
+				SQL:    s.SQL,
+				Count:  s.Count,
+				Errors: s.Errors,
+				Total:  s.Total.String(),
+				Max:    s.Max.String(),
+				P50:    s.P50.String(),
+				P95:    s.P95.String(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(res)
+	}
+}
+
+// ResetQueryStats implements AdminHTTPController.
+func (hc *adminHTTPController) ResetQueryStats() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		hc.tracer.ResetStats()
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func NewAdminHTTPController(
+	f *fiber.App,
+	levels *logger.Levels,
+	tracer postgres.QueryTracer,
+	config *AdminHTTPControllerConfig,
+	logger *logrus.Logger,
+) AdminHTTPController {
+	hc := &adminHTTPController{
+		f:      f,
+		levels: levels,
+		tracer: tracer,
+		config: config,
+		log:    logger.WithField("layer", "internal.controller.http.adminHTTPController"),
+	}
+	admin := hc.f.Group(hc.config.BasePath, hc.authorize())
+	admin.Get("/logger", hc.GetLogLevel())
+	admin.Put("/logger", hc.SetLogLevel())
+	admin.Get("/postgres/stats", hc.GetQueryStats())
+	admin.Delete("/postgres/stats", hc.ResetQueryStats())
+
+	return hc
+}
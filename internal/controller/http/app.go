@@ -52,17 +52,17 @@ func (hc *appHTTPController) CreateBook() func(*fiber.Ctx) error {
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), hc.config.Timeout)
+		ctx, cancel := context.WithTimeout(c.UserContext(), hc.config.Timeout)
 		defer cancel()
 		b, err := hc.books.New(ctx, book)
 		if err != nil {
 			if errors.Is(err, domain.ErrValidation) {
 				return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
 			}
-			hc.log.WithFields(structs.Map(book)).Error("cannot add new book")
+			requestLogger(c, hc.log).WithError(err).WithFields(structs.Map(book)).Error("cannot add new book")
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.ErrInternalServerError)
 		}
-		c.Location(c.Path() + "/" + b.ID.String())
+		c.Location(originalPath(c) + "/" + b.ID.String())
 		return c.SendStatus(fiber.StatusCreated)
 	}
 }
@@ -84,11 +84,11 @@ func (hc *appHTTPController) DeleteBook() func(*fiber.Ctx) error {
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), hc.config.Timeout)
+		ctx, cancel := context.WithTimeout(c.UserContext(), hc.config.Timeout)
 		defer cancel()
 		err = hc.books.Remove(ctx, bookID)
 		if err != nil {
-			hc.log.WithField("book_id", bookID).Error("cannot remove book")
+			requestLogger(c, hc.log).WithError(err).WithField("book_id", bookID).Error("cannot remove book")
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.ErrInternalServerError)
 		}
 		return c.SendStatus(fiber.StatusNoContent)
@@ -113,14 +113,14 @@ func (hc *appHTTPController) GetBook() func(*fiber.Ctx) error {
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), hc.config.Timeout)
+		ctx, cancel := context.WithTimeout(c.UserContext(), hc.config.Timeout)
 		defer cancel()
 		b, err := hc.books.View(ctx, bookID)
 		if err != nil {
 			if errors.Is(err, domain.ErrBookNotFound) {
 				return c.Status(fiber.StatusNotFound).JSON(fiber.ErrNotFound)
 			}
-			hc.log.WithField("book_id", bookID).Error("cannot view book")
+			requestLogger(c, hc.log).WithError(err).WithField("book_id", bookID).Error("cannot view book")
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.ErrInternalServerError)
 		}
 		return c.Status(fiber.StatusOK).JSON(b)
@@ -143,7 +143,7 @@ func (hc *appHTTPController) GetBook() func(*fiber.Ctx) error {
 //	@Router			/books [get]
 func (hc *appHTTPController) GetBooks() func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		ctx, cancel := context.WithTimeout(context.Background(), hc.config.Timeout)
+		ctx, cancel := context.WithTimeout(c.UserContext(), hc.config.Timeout)
 		defer cancel()
 		filters := new(domain.BookFilters)
 		err := c.QueryParser(filters)
@@ -155,7 +155,7 @@ func (hc *appHTTPController) GetBooks() func(*fiber.Ctx) error {
 			if errors.Is(err, domain.ErrValidation) {
 				return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
 			}
-			hc.log.WithFields(structs.Map(filters)).Error("cannot list books")
+			requestLogger(c, hc.log).WithError(err).WithFields(structs.Map(filters)).Error("cannot list books")
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.ErrInternalServerError)
 		}
 		return c.Status(fiber.StatusOK).JSON(p)
@@ -185,7 +185,7 @@ func (hc *appHTTPController) UpdateBook() func(*fiber.Ctx) error {
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.NewError(fiber.StatusBadRequest, err.Error()))
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), hc.config.Timeout)
+		ctx, cancel := context.WithTimeout(c.UserContext(), hc.config.Timeout)
 		defer cancel()
 		_, err = hc.books.Modify(ctx, book)
 		if err != nil {
@@ -195,10 +195,10 @@ func (hc *appHTTPController) UpdateBook() func(*fiber.Ctx) error {
 			if errors.Is(err, domain.ErrBookNotFound) {
 				return c.Status(fiber.StatusNotFound).JSON(fiber.ErrNotFound)
 			}
-			hc.log.WithFields(structs.Map(book)).Error("cannot modify book")
+			requestLogger(c, hc.log).WithError(err).WithFields(structs.Map(book)).Error("cannot modify book")
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.ErrInternalServerError)
 		}
-		c.Location(c.Path())
+		c.Location(originalPath(c))
 		return c.SendStatus(fiber.StatusNoContent)
 	}
 }
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"goapptemplate/pkg/logger"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LocalsRequestID is the requestid middleware default context key
+	LocalsRequestID = "requestid"
+	// LocalsPrincipal holds identity of the request author
+	LocalsPrincipal = "principal"
+
+	headerTraceparent = "traceparent"
+)
+
+// RequestLogger stores a logrus entry enriched with request ID, method, path,
+// principal and trace ID in request user context.
+//
+// Must be used after requestid middleware.
+func RequestLogger(log *logrus.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		fields := logrus.Fields{
+			logger.FieldMethod: c.Method(),
+			logger.FieldPath:   c.Path(),
+		}
+		if rid, ok := c.Locals(LocalsRequestID).(string); ok {
+			fields[logger.FieldRequestID] = rid
+		}
+		if p, ok := c.Locals(LocalsPrincipal).(string); ok && p != "" {
+			fields[logger.FieldUser] = p
+		}
+		if tid := traceID(c.Get(headerTraceparent)); tid != "" {
+			fields[logger.FieldTraceID] = tid
+		}
+		c.SetUserContext(logger.WithEntry(c.UserContext(), log.WithFields(fields)))
+		return c.Next()
+	}
+}
+
+// AccessLogger logs every request once handled, with latency, status and
+// values of request locals, e.g. request ID, under fields they are keyed by.
+//
+// Fields other than locals match fiberlogrus tags.
+func AccessLogger(log *logrus.Logger, locals map[string]string) fiber.Handler {
+	pid := os.Getpid()
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		bytesSent := 0
+		if c.Response().Header.ContentLength() >= 0 {
+			bytesSent = len(c.Response().Body())
+		}
+		fields := logrus.Fields{
+			"latency":   time.Since(start).String(),
+			"method":    c.Method(),
+			"url":       c.OriginalURL(),
+			"ua":        c.Get(fiber.HeaderUserAgent),
+			"bytesSent": bytesSent,
+			"pid":       pid,
+			"status":    c.Response().StatusCode(),
+			"route":     c.Route().Path,
+		}
+		for field, key := range locals {
+			if v, ok := c.Locals(key).(string); ok && v != "" {
+				fields[field] = v
+			}
+		}
+		log.WithFields(fields).Info()
+		return err
+	}
+}
+
+// ClientCertPrincipal stores subject of verified client certificate as request principal.
+//
+// Must be used before RequestLogger.
+func ClientCertPrincipal() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		state := c.Context().TLSConnectionState()
+		// Verified chains are empty when client certificate was not given or verified
+		if state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			c.Locals(LocalsPrincipal, state.VerifiedChains[0][0].Subject.String())
+		}
+		return c.Next()
+	}
+}
+
+// Principal returns request principal, Authorization header value is used
+// when principal is not resolved.
+func Principal(c *fiber.Ctx) string {
+	if p := VerifiedPrincipal(c); p != "" {
+		return p
+	}
+	return c.Get(fiber.HeaderAuthorization)
+}
+
+// VerifiedPrincipal returns principal resolved by middleware, e.g. subject of
+// verified client certificate, empty when not resolved.
+func VerifiedPrincipal(c *fiber.Ctx) string {
+	p, _ := c.Locals(LocalsPrincipal).(string)
+	return p
+}
+
+// originalPath returns request path before rewrites, e.g. listener prefix stripping.
+func originalPath(c *fiber.Ctx) string {
+	path, _, _ := strings.Cut(c.OriginalURL(), "?")
+	return path
+}
+
+// requestLogger returns request scoped entry enriched with matched route and base fields.
+func requestLogger(c *fiber.Ctx, base *logrus.Entry) *logrus.Entry {
+	return logger.Ctx(c.UserContext(), base).WithField(logger.FieldRoute, c.Route().Path)
+}
+
+// traceID extracts trace ID from W3C traceparent header value
+// (version-traceid-parentid-flags).
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestAccessLoggerLocals(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	f := fiber.New()
+	f.Use(AccessLogger(log, map[string]string{
+		"request_id": LocalsRequestID,
+		"tenant_id":  "tenant",
+	}))
+	f.Get("/books", func(c *fiber.Ctx) error {
+		c.Locals(LocalsRequestID, "rid")
+		c.Locals("tenant", "acme")
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	_, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/books", nil))
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	e := hook.LastEntry()
+	if e == nil {
+		t.Fatal("access log entry is not written")
+	}
+	want := logrus.Fields{
+		"request_id": "rid",
+		"tenant_id":  "acme",
+		"status":     fiber.StatusNoContent,
+		"route":      "/books",
+	}
+	for k, v := range want {
+		if e.Data[k] != v {
+			t.Errorf("field %s = %v, want %v", k, e.Data[k], v)
+		}
+	}
+}
@@ -12,4 +12,9 @@ var (
 
 	ErrBookName     = errors.New("invalid name")
 	ErrBookNotFound = errors.New("book not found")
+
+	ErrTenantID           = errors.New("invalid tenant ID")
+	ErrTenantNotFound     = errors.New("tenant not found")
+	ErrTenantExists       = errors.New("tenant already exists")
+	ErrTenantConfirmation = errors.New("tenant ID is not confirmed")
 )
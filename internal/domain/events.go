@@ -0,0 +1,18 @@
+package domain
+
+import "github.com/google/uuid"
+
+// Aggregate and event types of book domain events, events are published after
+// transaction of the change commits.
+const (
+	AggregateBook = "book"
+
+	EventBookCreated = "BookCreated"
+	EventBookUpdated = "BookUpdated"
+	EventBookDeleted = "BookDeleted"
+)
+
+// BookDeleted is payload of EventBookDeleted, payload of the other book events is Book.
+type BookDeleted struct {
+	ID uuid.UUID `json:"id"`
+}
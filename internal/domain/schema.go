@@ -1,5 +1,14 @@
 package domain
 
 const (
-	SchemaApp = "app_schema"
+	SchemaApp      = "app_schema"
+	SchemaRegistry = "registry_schema"
+
+	// schemaTenantPrefix is prepended to tenant ID to get tenant schema
+	schemaTenantPrefix = "tenant_"
 )
+
+// SchemaTenant returns schema of tenant, tenant ID must be valid.
+func SchemaTenant(tenantID string) string {
+	return schemaTenantPrefix + tenantID
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+)
+
+// tenantIDRegexp keeps tenant schema a valid unquoted identifier
+var tenantIDRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// Tenant has its own schema, or shares application schema with other tenants
+// isolated by row level security when Schema is empty.
+type Tenant struct {
+	ID        string    `json:"id"`
+	Schema    string    `json:"schema,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (t Tenant) Validate() error {
+	if !tenantIDRegexp.MatchString(t.ID) {
+		return ErrTenantID
+	}
+	return nil
+}
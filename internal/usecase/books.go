@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"goapptemplate/internal/domain"
+	"goapptemplate/pkg/logger"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -19,6 +20,7 @@ type booksUsecase struct {
 func (u *booksUsecase) List(ctx context.Context, filters *domain.BookFilters) (*domain.BookPage, error) {
 	err := filters.Validate()
 	if err != nil {
+		u.logger(ctx).WithError(err).Debug("invalid book filters")
 		return nil, fmt.Errorf("%w: %w", domain.ErrValidation, err)
 	}
 	p, err := u.repo.RetrievePage(ctx, filters)
@@ -32,6 +34,7 @@ func (u *booksUsecase) List(ctx context.Context, filters *domain.BookFilters) (*
 func (u *booksUsecase) Modify(ctx context.Context, book *domain.Book) (*domain.Book, error) {
 	err := book.Validate()
 	if err != nil {
+		u.logger(ctx).WithError(err).Debug("invalid book")
 		return nil, fmt.Errorf("%w: %w", domain.ErrValidation, err)
 	}
 	b, err := u.repo.Update(ctx, book)
@@ -45,6 +48,7 @@ func (u *booksUsecase) Modify(ctx context.Context, book *domain.Book) (*domain.B
 func (u *booksUsecase) New(ctx context.Context, book *domain.Book) (*domain.Book, error) {
 	err := book.Validate()
 	if err != nil {
+		u.logger(ctx).WithError(err).Debug("invalid book")
 		return nil, fmt.Errorf("%w: %w", domain.ErrValidation, err)
 	}
 	book.ID = uuid.New()
@@ -73,6 +77,11 @@ func (u *booksUsecase) View(ctx context.Context, bookID uuid.UUID) (*domain.Book
 	return b, nil
 }
 
+// logger returns request scoped entry of the usecase layer.
+func (u *booksUsecase) logger(ctx context.Context) *logrus.Entry {
+	return logger.Ctx(ctx, u.log)
+}
+
 func NewBooks(repo BooksRepo, logger *logrus.Logger) Books {
 	return &booksUsecase{
 		repo: repo,
@@ -22,4 +22,10 @@ type (
 		Update(ctx context.Context, book *domain.Book) (*domain.Book, error)
 		Remove(ctx context.Context, bookID uuid.UUID) error
 	}
+	TenantsRepo interface {
+		Store(ctx context.Context, tenant *domain.Tenant) (*domain.Tenant, error)
+		Retrieve(ctx context.Context, tenantID string) (*domain.Tenant, error)
+		RetrieveAll(ctx context.Context) ([]*domain.Tenant, error)
+		Remove(ctx context.Context, tenantID string) error
+	}
 )
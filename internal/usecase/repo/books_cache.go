@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"goapptemplate/internal/domain"
+	"goapptemplate/internal/usecase"
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/postgres"
+	"goapptemplate/pkg/tenancy"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const booksCacheKeyPrefix = "books:item:"
+
+type BooksCacheRepoConfig struct {
+	TTL time.Duration
+	// Jitter is the max random duration added to TTL, so that entries
+	// cached at once do not expire at once
+	Jitter time.Duration
+	// Cooldown is the duration cache is bypassed for after storage failure
+	Cooldown time.Duration
+	// Timeout of repository call shared by concurrent misses, which is not
+	// canceled with the context of the caller that started it
+	Timeout time.Duration
+}
+
+// booksCacheRepo is a read-through cache decorator of usecase.BooksRepo.
+type booksCacheRepo struct {
+	repo    usecase.BooksRepo
+	storage fiber.Storage
+	config  *BooksCacheRepoConfig
+	group   singleflight.Group
+	// unavailableUntil is unix nano time until which storage is bypassed
+	unavailableUntil atomic.Int64
+	log              *logrus.Entry
+}
+
+// Remove implements usecase.BooksRepo.
+func (repo *booksCacheRepo) Remove(ctx context.Context, bookID uuid.UUID) error {
+	err := repo.repo.Remove(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	repo.evict(ctx, bookID)
+	return nil
+}
+
+// Retrieve implements usecase.BooksRepo.
+func (repo *booksCacheRepo) Retrieve(ctx context.Context, bookID uuid.UUID) (*domain.Book, error) {
+	if !repo.available() {
+		return repo.repo.Retrieve(ctx, bookID)
+	}
+	key := bookKey(ctx, bookID)
+	b, err := repo.storage.Get(key)
+	if err != nil {
+		repo.fail(ctx, err, "cannot get cached book")
+		return repo.repo.Retrieve(ctx, bookID)
+	}
+	if b != nil {
+		book := &domain.Book{}
+		err = json.Unmarshal(b, book)
+		if err == nil {
+			return book, nil
+		}
+		repo.logger(ctx).WithError(err).WithField("book_id", bookID).Warn("cannot unmarshal cached book")
+	}
+	// Concurrent misses of the same book share a single repository call, each
+	// caller stops waiting for it when its own context is done. Book is read from
+	// primary, replicas may still return the book evicted by the last write.
+	ch := repo.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(postgres.WithPrimary(context.WithoutCancel(ctx)), repo.config.Timeout)
+		defer cancel()
+		book, err := repo.repo.Retrieve(ctx, bookID)
+		if err != nil {
+			return nil, err
+		}
+		repo.set(ctx, book)
+		return book, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*domain.Book), nil
+	}
+}
+
+// RetrievePage implements usecase.BooksRepo.
+func (repo *booksCacheRepo) RetrievePage(ctx context.Context, filters *domain.BookFilters) (*domain.BookPage, error) {
+	return repo.repo.RetrievePage(ctx, filters)
+}
+
+// Store implements usecase.BooksRepo.
+func (repo *booksCacheRepo) Store(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	b, err := repo.repo.Store(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+	repo.evict(ctx, b.ID)
+	return b, nil
+}
+
+// Update implements usecase.BooksRepo.
+func (repo *booksCacheRepo) Update(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	b, err := repo.repo.Update(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+	repo.evict(ctx, book.ID)
+	return b, nil
+}
+
+func (repo *booksCacheRepo) set(ctx context.Context, book *domain.Book) {
+	if !repo.available() {
+		return
+	}
+	b, err := json.Marshal(book)
+	if err != nil {
+		repo.logger(ctx).WithError(err).WithField("book_id", book.ID).Warn("cannot marshal book")
+		return
+	}
+	ttl := repo.config.TTL
+	if repo.config.Jitter > 0 {
+		ttl += time.Duration(rand.Int63n(int64(repo.config.Jitter)))
+	}
+	err = repo.storage.Set(bookKey(ctx, book.ID), b, ttl)
+	if err != nil {
+		repo.fail(ctx, err, "cannot cache book")
+	}
+}
+
+func (repo *booksCacheRepo) evict(ctx context.Context, bookID uuid.UUID) {
+	// Evicted even when storage is considered unavailable, stale entries are worse than a failed call
+	err := repo.storage.Delete(bookKey(ctx, bookID))
+	if err != nil {
+		repo.fail(ctx, err, "cannot evict cached book")
+	}
+}
+
+// bookKey returns cache key of book, books of different tenants never share keys.
+func bookKey(ctx context.Context, bookID uuid.UUID) string {
+	if tenantID := tenancy.FromContext(ctx); tenantID != "" {
+		return booksCacheKeyPrefix + tenantID + "/" + bookID.String()
+	}
+	return booksCacheKeyPrefix + bookID.String()
+}
+
+func (repo *booksCacheRepo) available() bool {
+	return time.Now().UnixNano() >= repo.unavailableUntil.Load()
+}
+
+// fail makes cache bypassed for cooldown duration.
+func (repo *booksCacheRepo) fail(ctx context.Context, err error, msg string) {
+	repo.unavailableUntil.Store(time.Now().Add(repo.config.Cooldown).UnixNano())
+	repo.logger(ctx).WithError(errors.Wrap(err, msg)).WithField("cooldown", repo.config.Cooldown.String()).Warn("books cache is bypassed")
+}
+
+// logger returns request scoped entry of the repository layer.
+func (repo *booksCacheRepo) logger(ctx context.Context) *logrus.Entry {
+	return logger.Ctx(ctx, repo.log)
+}
+
+func NewBooksCacheRepo(repo usecase.BooksRepo, storage fiber.Storage, config *BooksCacheRepoConfig, logger *logrus.Logger) usecase.BooksRepo {
+	return &booksCacheRepo{
+		repo:    repo,
+		storage: storage,
+		config:  config,
+		log:     logger.WithField("layer", "internal.usecase.repo.booksCacheRepo"),
+	}
+}
@@ -0,0 +1,241 @@
+package repo
+
+import (
+	"context"
+	"goapptemplate/internal/domain"
+	"goapptemplate/pkg/lru"
+	"goapptemplate/pkg/postgres"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeBooksRepo serves books from memory, Retrieve blocks while release is set
+// and not closed.
+type fakeBooksRepo struct {
+	mu        sync.Mutex
+	books     map[uuid.UUID]*domain.Book
+	retrieves atomic.Int32
+	// primary counts retrieves which read from primary
+	primary atomic.Int32
+	started   chan struct{}
+	release   chan struct{}
+}
+
+func (r *fakeBooksRepo) Remove(ctx context.Context, bookID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.books, bookID)
+	return nil
+}
+
+func (r *fakeBooksRepo) Retrieve(ctx context.Context, bookID uuid.UUID) (*domain.Book, error) {
+	r.retrieves.Add(1)
+	if postgres.Primary(ctx) {
+		r.primary.Add(1)
+	}
+	if r.release != nil {
+		r.started <- struct{}{}
+		<-r.release
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.books[bookID]
+	if !ok {
+		return nil, domain.ErrBookNotFound
+	}
+	c := *b
+	return &c, nil
+}
+
+func (r *fakeBooksRepo) RetrievePage(ctx context.Context, filters *domain.BookFilters) (*domain.BookPage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeBooksRepo) Store(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *book
+	r.books[book.ID] = &c
+	return book, nil
+}
+
+func (r *fakeBooksRepo) Update(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	return r.Store(ctx, book)
+}
+
+func newTestBooksCacheRepo(t *testing.T, books ...*domain.Book) (*fakeBooksRepo, *booksCacheRepo) {
+	t.Helper()
+	fake := &fakeBooksRepo{books: make(map[uuid.UUID]*domain.Book)}
+	for _, b := range books {
+		fake.books[b.ID] = b
+	}
+	storage := lru.New(0, time.Minute)
+	t.Cleanup(func() { _ = storage.Close() })
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	cache := NewBooksCacheRepo(fake, storage, &BooksCacheRepoConfig{
+		TTL:      time.Minute,
+		Cooldown: time.Second,
+		Timeout:  time.Second,
+	}, l)
+	return fake, cache.(*booksCacheRepo)
+}
+
+func TestBooksCacheRepoRetrieve(t *testing.T) {
+	book := &domain.Book{ID: uuid.New(), Name: "name"}
+	fake, cache := newTestBooksCacheRepo(t, book)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		b, err := cache.Retrieve(ctx, book.ID)
+		if err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+		if b.Name != book.Name {
+			t.Fatalf("Retrieve() name = %q, want %q", b.Name, book.Name)
+		}
+	}
+	if n := fake.retrieves.Load(); n != 1 {
+		t.Fatalf("repository retrieves = %d, want 1", n)
+	}
+	// Replicas may still return books evicted by writes, cache is filled from primary
+	if n := fake.primary.Load(); n != 1 {
+		t.Fatalf("repository retrieves from primary = %d, want 1", n)
+	}
+
+	_, err := cache.Retrieve(ctx, uuid.New())
+	if !errors.Is(err, domain.ErrBookNotFound) {
+		t.Fatalf("Retrieve() of missing book error = %v, want %v", err, domain.ErrBookNotFound)
+	}
+}
+
+func TestBooksCacheRepoRetrieveCoalesced(t *testing.T) {
+	book := &domain.Book{ID: uuid.New(), Name: "name"}
+	fake, cache := newTestBooksCacheRepo(t, book)
+	fake.started = make(chan struct{}, 1)
+	fake.release = make(chan struct{})
+
+	const callers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	call := func() {
+		defer wg.Done()
+		_, err := cache.Retrieve(context.Background(), book.ID)
+		errs <- err
+	}
+	wg.Add(1)
+	go call()
+	<-fake.started
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go call()
+	}
+	// Let the rest of callers join the load in flight
+	time.Sleep(50 * time.Millisecond)
+	close(fake.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+	}
+	if n := fake.retrieves.Load(); n != 1 {
+		t.Fatalf("repository retrieves = %d, want 1", n)
+	}
+}
+
+func TestBooksCacheRepoRetrieveCallerCanceled(t *testing.T) {
+	book := &domain.Book{ID: uuid.New(), Name: "name"}
+	fake, cache := newTestBooksCacheRepo(t, book)
+	fake.started = make(chan struct{}, 1)
+	fake.release = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.Retrieve(ctx, book.ID)
+		first <- err
+	}()
+	<-fake.started
+	second := make(chan error, 1)
+	go func() {
+		_, err := cache.Retrieve(context.Background(), book.ID)
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Caller which started the load goes away, waiting callers are not affected
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Retrieve() of canceled caller error = %v, want %v", err, context.Canceled)
+	}
+	close(fake.release)
+	if err := <-second; err != nil {
+		t.Fatalf("Retrieve() of waiting caller error = %v", err)
+	}
+	if n := fake.retrieves.Load(); n != 1 {
+		t.Fatalf("repository retrieves = %d, want 1", n)
+	}
+}
+
+func TestBooksCacheRepoEvictOnWrite(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		write func(cache *booksCacheRepo, book *domain.Book) error
+	}{
+		{
+			name: "store",
+			write: func(cache *booksCacheRepo, book *domain.Book) error {
+				_, err := cache.Store(ctx, book)
+				return err
+			},
+		},
+		{
+			name: "update",
+			write: func(cache *booksCacheRepo, book *domain.Book) error {
+				_, err := cache.Update(ctx, book)
+				return err
+			},
+		},
+		{
+			name: "remove",
+			write: func(cache *booksCacheRepo, book *domain.Book) error {
+				return cache.Remove(ctx, book.ID)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := &domain.Book{ID: uuid.New(), Name: "name"}
+			fake, cache := newTestBooksCacheRepo(t, book)
+			_, err := cache.Retrieve(ctx, book.ID)
+			if err != nil {
+				t.Fatalf("Retrieve() error = %v", err)
+			}
+			err = tt.write(cache, &domain.Book{ID: book.ID, Name: "changed"})
+			if err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+			b, _ := cache.storage.Get(bookKey(ctx, book.ID))
+			if b != nil {
+				t.Fatalf("cached book = %s, want evicted", b)
+			}
+			_, _ = cache.Retrieve(ctx, book.ID)
+			if n := fake.retrieves.Load(); n != 2 {
+				t.Fatalf("repository retrieves = %d, want 2", n)
+			}
+		})
+	}
+}
@@ -2,10 +2,13 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"goapptemplate/gen/app/db"
 	"goapptemplate/internal/domain"
 	"goapptemplate/internal/usecase"
+	"goapptemplate/pkg/logger"
 	"goapptemplate/pkg/postgres"
+	"goapptemplate/pkg/tenancy"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -21,41 +24,24 @@ type booksPostgresRepo struct {
 
 // Remove implements usecase.BooksRepo.
 func (repo *booksPostgresRepo) Remove(ctx context.Context, bookID uuid.UUID) error {
-	conn, tx, err := repo.BeginTx(ctx)
-	if err != nil {
-		return errors.Wrap(err, "cannot begin tx")
-	}
-	defer conn.Release()
-	defer tx.Rollback(ctx)
-
-	q := db.New(conn).WithTx(tx)
-
-	err = q.DeleteBookWhereID(ctx, pgtype.UUID{
-		Bytes: bookID,
-		Valid: true,
+	return repo.withTx(ctx, pgx.TxOptions{}, func(ctx context.Context, q *db.Queries) error {
+		n, err := q.DeleteBookWhereID(ctx, pgtype.UUID{
+			Bytes: bookID,
+			Valid: true,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "cannot delete book where ID=%s", bookID)
+		}
+		if n == 0 {
+			return nil
+		}
+		return repo.event(ctx, q, domain.EventBookDeleted, bookID, &domain.BookDeleted{ID: bookID})
 	})
-	if err != nil {
-		return errors.Wrapf(err, "cannot delete book where ID=%s", bookID)
-	}
-
-	err = repo.EndTx(ctx, tx)
-	if err != nil {
-		return errors.Wrap(err, "cannot end tx")
-	}
-
-	return nil
 }
 
 // Retrieve implements usecase.BooksRepo.
 func (repo *booksPostgresRepo) Retrieve(ctx context.Context, bookID uuid.UUID) (*domain.Book, error) {
-	conn, tx, err := repo.BeginTx(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot begin tx")
-	}
-	defer conn.Release()
-	defer tx.Rollback(ctx)
-
-	q := db.New(conn).WithTx(tx)
+	q := db.New(repo.Reader(ctx))
 
 	row, err := q.SelectBookWhereID(ctx, pgtype.UUID{
 		Bytes: bookID,
@@ -63,48 +49,26 @@ func (repo *booksPostgresRepo) Retrieve(ctx context.Context, bookID uuid.UUID) (
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
+			repo.logger(ctx).WithField("book_id", bookID).Debug("book not found")
 			return nil, domain.ErrBookNotFound
 		}
 		return nil, errors.Wrapf(err, "cannot select book where ID=%s", bookID)
 	}
-	book := &domain.Book{
+	return &domain.Book{
 		ID:          row.ID.Bytes,
 		Name:        row.Name,
 		Description: row.Description.String,
 		CreatedAt:   row.CreatedAt.Time,
 		UpdatedAt:   row.UpdatedAt.Time,
-	}
-
-	err = repo.EndTx(ctx, tx)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot end tx")
-	}
-
-	return book, nil
+	}, nil
 }
 
 // RetrievePage implements usecase.BooksRepo.
 func (repo *booksPostgresRepo) RetrievePage(ctx context.Context, filters *domain.BookFilters) (*domain.BookPage, error) {
-	conn, tx, err := repo.BeginTx(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot begin tx")
-	}
-	defer conn.Release()
-	defer tx.Rollback(ctx)
-
-	q := db.New(conn).WithTx(tx)
+	q := db.New(repo.Reader(ctx))
 
-	total, err := q.SelectBooksCount(ctx, db.SelectBooksCountParams{
-		Name: "%" + filters.Name + "%",
-		Description: pgtype.Text{
-			String: "%" + filters.Description + "%",
-			Valid:  true,
-		},
-	})
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot select books count")
-	}
-	rows, err := q.SelectBooks(ctx, db.SelectBooksParams{
+	// Total is counted by the page query, so that both come from one snapshot
+	rows, err := q.SelectBooksPage(ctx, db.SelectBooksPageParams{
 		Name: "%" + filters.Name + "%",
 		Description: pgtype.Text{
 			String: "%" + filters.Description + "%",
@@ -114,7 +78,23 @@ func (repo *booksPostgresRepo) RetrievePage(ctx context.Context, filters *domain
 		Lim:  filters.Limit,
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot select books")
+		return nil, errors.Wrap(err, "cannot select books page")
+	}
+	var total int64
+	if len(rows) > 0 {
+		total = rows[0].Total
+	} else if filters.Offset > 0 {
+		// Page past the last book carries no total
+		total, err = q.SelectBooksCount(ctx, db.SelectBooksCountParams{
+			Name: "%" + filters.Name + "%",
+			Description: pgtype.Text{
+				String: "%" + filters.Description + "%",
+				Valid:  true,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot select books count")
+		}
 	}
 	var books []*domain.Book
 	for _, b := range rows {
@@ -128,11 +108,6 @@ func (repo *booksPostgresRepo) RetrievePage(ctx context.Context, filters *domain
 		books = append(books, book)
 	}
 
-	err = repo.EndTx(ctx, tx)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot end tx")
-	}
-
 	return &domain.BookPage{
 		Page: domain.Page{
 			Total:  total,
@@ -148,94 +123,114 @@ func (repo *booksPostgresRepo) RetrievePage(ctx context.Context, filters *domain
 
 // Store implements usecase.BooksRepo.
 func (repo *booksPostgresRepo) Store(ctx context.Context, book *domain.Book) (*domain.Book, error) {
-	conn, tx, err := repo.BeginTx(ctx)
+	var stored *domain.Book
+	err := repo.withTx(ctx, pgx.TxOptions{}, func(ctx context.Context, q *db.Queries) error {
+		row, err := q.InsertBook(ctx, db.InsertBookParams{
+			ID: pgtype.UUID{
+				Bytes: book.ID,
+				Valid: true,
+			},
+			Name: book.Name,
+			Description: pgtype.Text{
+				String: book.Description,
+				Valid:  true,
+			},
+		})
+		if err != nil {
+			return errors.Wrap(err, "cannot insert book")
+		}
+		stored = &domain.Book{
+			ID:          row.ID.Bytes,
+			Name:        row.Name,
+			Description: row.Description.String,
+			CreatedAt:   row.CreatedAt.Time,
+			UpdatedAt:   row.UpdatedAt.Time,
+		}
+		return repo.event(ctx, q, domain.EventBookCreated, stored.ID, stored)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot begin tx")
+		return nil, err
 	}
-	defer conn.Release()
-	defer tx.Rollback(ctx)
-
-	q := db.New(conn).WithTx(tx)
+	return stored, nil
+}
 
-	row, err := q.InsertBook(ctx, db.InsertBookParams{
-		ID: pgtype.UUID{
+// Update implements usecase.BooksRepo.
+func (repo *booksPostgresRepo) Update(ctx context.Context, book *domain.Book) (*domain.Book, error) {
+	var updated *domain.Book
+	err := repo.withTx(ctx, pgx.TxOptions{}, func(ctx context.Context, q *db.Queries) error {
+		err := q.UpdateBookWhereID(ctx, db.UpdateBookWhereIDParams{
+			Name: book.Name,
+			Description: pgtype.Text{
+				String: book.Description,
+				Valid:  true,
+			},
+			ID: pgtype.UUID{
+				Bytes: book.ID,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "cannot update book where ID=%s", book.ID)
+		}
+		row, err := q.SelectBookWhereID(ctx, pgtype.UUID{
 			Bytes: book.ID,
 			Valid: true,
-		},
-		Name: book.Name,
-		Description: pgtype.Text{
-			String: book.Description,
-			Valid:  true,
-		},
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				repo.logger(ctx).WithField("book_id", book.ID).Debug("book not found")
+				return domain.ErrBookNotFound
+			}
+			return errors.Wrapf(err, "cannot select book where ID=%s", book.ID)
+		}
+		updated = &domain.Book{
+			ID:          row.ID.Bytes,
+			Name:        row.Name,
+			Description: row.Description.String,
+			CreatedAt:   row.CreatedAt.Time,
+			UpdatedAt:   row.UpdatedAt.Time,
+		}
+		return repo.event(ctx, q, domain.EventBookUpdated, updated.ID, updated)
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot insert book")
-	}
-	book = &domain.Book{
-		ID:          row.ID.Bytes,
-		Name:        row.Name,
-		Description: row.Description.String,
-		CreatedAt:   row.CreatedAt.Time,
-		UpdatedAt:   row.UpdatedAt.Time,
-	}
-
-	err = repo.EndTx(ctx, tx)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot end tx")
+		return nil, err
 	}
-
-	return book, nil
+	return updated, nil
 }
 
-// Update implements usecase.BooksRepo.
-func (repo *booksPostgresRepo) Update(ctx context.Context, book *domain.Book) (*domain.Book, error) {
-	conn, tx, err := repo.BeginTx(ctx)
+// event writes book event to outbox in transaction of q, it is published once
+// the transaction commits.
+func (repo *booksPostgresRepo) event(ctx context.Context, q *db.Queries, eventType string, bookID uuid.UUID, payload interface{}) error {
+	data, err := json.Marshal(payload)
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot begin tx")
+		return errors.Wrapf(err, "cannot marshal %s event", eventType)
 	}
-	defer conn.Release()
-	defer tx.Rollback(ctx)
-
-	q := db.New(conn).WithTx(tx)
-
-	err = q.UpdateBookWhereID(ctx, db.UpdateBookWhereIDParams{
-		Name: book.Name,
-		Description: pgtype.Text{
-			String: book.Description,
-			Valid:  true,
-		},
-		ID: pgtype.UUID{
-			Bytes: book.ID,
+	err = q.InsertOutboxEvent(ctx, db.InsertOutboxEventParams{
+		AggregateType: domain.AggregateBook,
+		AggregateID: pgtype.UUID{
+			Bytes: bookID,
 			Valid: true,
 		},
+		EventType: eventType,
+		TenantID:  tenancy.FromContext(ctx),
+		Payload:   data,
 	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot update book where ID=%s", book.ID)
-	}
-	row, err := q.SelectBookWhereID(ctx, pgtype.UUID{
-		Bytes: book.ID,
-		Valid: true,
-	})
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrBookNotFound
-		}
-		return nil, errors.Wrapf(err, "cannot select book where ID=%s", book.ID)
-	}
-	book = &domain.Book{
-		ID:          row.ID.Bytes,
-		Name:        row.Name,
-		Description: row.Description.String,
-		CreatedAt:   row.CreatedAt.Time,
-		UpdatedAt:   row.UpdatedAt.Time,
+		return errors.Wrapf(err, "cannot insert %s event", eventType)
 	}
+	return nil
+}
 
-	err = repo.EndTx(ctx, tx)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot end tx")
-	}
+// withTx runs fn with queries of transaction begun with opts, see postgres.DB.WithTx.
+func (repo *booksPostgresRepo) withTx(ctx context.Context, opts pgx.TxOptions, fn func(ctx context.Context, q *db.Queries) error) error {
+	return repo.WithTx(ctx, opts, func(ctx context.Context, tx pgx.Tx) error {
+		return fn(ctx, db.New(tx))
+	})
+}
 
-	return book, nil
+// logger returns request scoped entry of the repository layer.
+func (repo *booksPostgresRepo) logger(ctx context.Context) *logrus.Entry {
+	return logger.Ctx(ctx, repo.log)
 }
 
 func NewBooksPostgresRepo(db postgres.DB, logger *logrus.Logger) usecase.BooksRepo {
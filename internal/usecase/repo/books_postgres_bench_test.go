@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"goapptemplate/gen/app/db"
+	"goapptemplate/pkg/postgres"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// benchBooks is the number of books benchmarks query
+const benchBooks = 1000
+
+// selectBooksTx is the page query counted separately, as books were selected in
+// repeatable read transaction before the page query counted them.
+const selectBooksTx = `SELECT id, name, description, created_at, updated_at, tenant_id
+FROM books
+WHERE name LIKE $1
+    AND description LIKE $2
+ORDER BY created_at DESC
+LIMIT $4 OFFSET $3
+`
+
+// newBenchPostgres returns db with benchBooks books of tenant of returned ctx.
+func newBenchPostgres(b *testing.B) (context.Context, *postgres.PostgresDB, pgtype.UUID) {
+	b.Helper()
+	pg := newTestPostgres(b)
+	ctx := postgres.WithTenantID(context.Background(), "bench")
+	var id pgtype.UUID
+	testTx(b, ctx, pg, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `insert into books (id, name, description)
+			select gen_random_uuid(), 'book ' || i, 'description ' || i from generate_series(1, $1) i`, benchBooks)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, "select id from books limit 1").Scan(&id)
+	})
+	return ctx, pg, id
+}
+
+func BenchmarkBooksRetrieve(b *testing.B) {
+	ctx, pg, id := newBenchPostgres(b)
+	b.Run("tx", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := pg.WithTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(ctx context.Context, tx pgx.Tx) error {
+				_, err := db.New(tx).SelectBookWhereID(ctx, id)
+				return err
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reader", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := db.New(pg.Reader(ctx)).SelectBookWhereID(ctx, id)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBooksRetrievePage(b *testing.B) {
+	ctx, pg, _ := newBenchPostgres(b)
+	name := "%book%"
+	description := pgtype.Text{String: "%description%", Valid: true}
+	b.Run("tx", func(b *testing.B) {
+		opts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+		for i := 0; i < b.N; i++ {
+			err := pg.WithTx(ctx, opts, func(ctx context.Context, tx pgx.Tx) error {
+				_, err := db.New(tx).SelectBooksCount(ctx, db.SelectBooksCountParams{
+					Name:        name,
+					Description: description,
+				})
+				if err != nil {
+					return err
+				}
+				rows, err := tx.Query(ctx, selectBooksTx, name, description, 0, 20)
+				if err != nil {
+					return err
+				}
+				_, err = pgx.CollectRows(rows, pgx.RowToStructByPos[db.Book])
+				return err
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reader", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := db.New(pg.Reader(ctx)).SelectBooksPage(ctx, db.SelectBooksPageParams{
+				Name:        name,
+				Description: description,
+				Ofst:        0,
+				Lim:         20,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package repo
+
+import (
+	"context"
+	"goapptemplate/gen/app/db"
+	"goapptemplate/pkg/postgres"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// errInsufficientPrivilege is raised when row violates WITH CHECK of a policy
+const errInsufficientPrivilege = "42501"
+
+func insertTestBook(t *testing.T, ctx context.Context, pg *postgres.PostgresDB, name string) pgtype.UUID {
+	t.Helper()
+	id := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	testTx(t, ctx, pg, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := db.New(tx).InsertBook(ctx, db.InsertBookParams{
+			ID:          id,
+			Name:        name,
+			Description: pgtype.Text{String: name, Valid: true},
+		})
+		return err
+	})
+	return id
+}
+
+func TestBooksRowLevelSecurity(t *testing.T) {
+	pg := newTestPostgres(t)
+	ctxA := postgres.WithTenantID(context.Background(), "a")
+	ctxB := postgres.WithTenantID(context.Background(), "b")
+	bookA := insertTestBook(t, ctxA, pg, "book a")
+	bookB := insertTestBook(t, ctxB, pg, "book b")
+
+	queriers := []struct {
+		name string
+		run  func(t *testing.T, fn func(q *db.Queries) error)
+	}{
+		{
+			name: "transaction",
+			run: func(t *testing.T, fn func(q *db.Queries) error) {
+				err := pg.WithTx(ctxA, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(ctx context.Context, tx pgx.Tx) error {
+					return fn(db.New(tx))
+				})
+				if err != nil {
+					t.Fatalf("transaction error = %v", err)
+				}
+			},
+		},
+		{
+			name: "reader",
+			run: func(t *testing.T, fn func(q *db.Queries) error) {
+				if err := fn(db.New(pg.Reader(ctxA))); err != nil {
+					t.Fatalf("reader error = %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range queriers {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, func(q *db.Queries) error {
+				b, err := q.SelectBookWhereID(ctxA, bookA)
+				if err != nil {
+					return errors.Wrap(err, "SelectBookWhereID() of own book")
+				}
+				if b.TenantID != "a" {
+					t.Errorf("SelectBookWhereID() tenant = %q, want %q", b.TenantID, "a")
+				}
+				_, err = q.SelectBookWhereID(ctxA, bookB)
+				if !errors.Is(err, pgx.ErrNoRows) {
+					t.Errorf("SelectBookWhereID() of foreign book error = %v, want %v", err, pgx.ErrNoRows)
+				}
+				rows, err := q.SelectBooksPage(ctxA, db.SelectBooksPageParams{
+					Name:        "%",
+					Description: pgtype.Text{String: "%", Valid: true},
+					Ofst:        0,
+					Lim:         10,
+				})
+				if err != nil {
+					return errors.Wrap(err, "SelectBooksPage()")
+				}
+				if len(rows) != 1 || rows[0].ID != bookA || rows[0].Total != 1 {
+					t.Errorf("SelectBooksPage() = %d rows, want only book of tenant with total 1", len(rows))
+				}
+				return nil
+			})
+		})
+	}
+
+	t.Run("insert foreign tenant", func(t *testing.T) {
+		err := pg.WithTx(ctxA, pgx.TxOptions{}, func(ctx context.Context, tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "insert into books (id, name, tenant_id) values ($1, 'foreign', 'b')", uuid.New())
+			return err
+		})
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != errInsufficientPrivilege {
+			t.Fatalf("insert error = %v, want %s", err, errInsufficientPrivilege)
+		}
+	})
+
+	t.Run("update foreign book", func(t *testing.T) {
+		testTx(t, ctxA, pg, func(ctx context.Context, tx pgx.Tx) error {
+			tag, err := tx.Exec(ctx, "update books set name = 'changed' where id = $1", bookB)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() != 0 {
+				t.Errorf("update rows affected = %d, want 0", tag.RowsAffected())
+			}
+			return nil
+		})
+	})
+
+	t.Run("without tenant", func(t *testing.T) {
+		// Tenant setting is local to transaction, it does not leak to the next
+		// statement run on the same pooled connection
+		ctx := context.Background()
+		var n int
+		err := pg.Reader(ctx).QueryRow(ctx, "select count(*) from books").Scan(&n)
+		if err != nil {
+			t.Fatalf("count error = %v", err)
+		}
+		if n != 0 {
+			t.Fatalf("count without tenant = %d, want 0", n)
+		}
+	})
+}
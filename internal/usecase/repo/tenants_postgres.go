@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"goapptemplate/gen/registry/db"
+	"goapptemplate/internal/domain"
+	"goapptemplate/internal/usecase"
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type tenantsPostgresRepo struct {
+	postgres.DB
+	log *logrus.Entry
+}
+
+// Remove implements usecase.TenantsRepo.
+func (repo *tenantsPostgresRepo) Remove(ctx context.Context, tenantID string) error {
+	return repo.withTx(ctx, pgx.TxOptions{}, func(ctx context.Context, q *db.Queries) error {
+		err := q.DeleteTenantWhereID(ctx, tenantID)
+		if err != nil {
+			return errors.Wrapf(err, "cannot delete tenant where ID=%s", tenantID)
+		}
+		return nil
+	})
+}
+
+// Retrieve implements usecase.TenantsRepo.
+func (repo *tenantsPostgresRepo) Retrieve(ctx context.Context, tenantID string) (*domain.Tenant, error) {
+	q := db.New(repo.Reader(ctx))
+
+	row, err := q.SelectTenantWhereID(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			repo.logger(ctx).WithField("tenant_id", tenantID).Debug("tenant not found")
+			return nil, domain.ErrTenantNotFound
+		}
+		return nil, errors.Wrapf(err, "cannot select tenant where ID=%s", tenantID)
+	}
+	return tenant(row), nil
+}
+
+// RetrieveAll implements usecase.TenantsRepo.
+func (repo *tenantsPostgresRepo) RetrieveAll(ctx context.Context) ([]*domain.Tenant, error) {
+	q := db.New(repo.Reader(ctx))
+
+	rows, err := q.SelectTenants(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot select tenants")
+	}
+	tenants := make([]*domain.Tenant, 0, len(rows))
+	for _, row := range rows {
+		tenants = append(tenants, tenant(row))
+	}
+	return tenants, nil
+}
+
+// Store implements usecase.TenantsRepo.
+func (repo *tenantsPostgresRepo) Store(ctx context.Context, t *domain.Tenant) (*domain.Tenant, error) {
+	var stored *domain.Tenant
+	err := repo.withTx(ctx, pgx.TxOptions{}, func(ctx context.Context, q *db.Queries) error {
+		row, err := q.InsertTenant(ctx, db.InsertTenantParams{
+			ID: t.ID,
+			SchemaName: pgtype.Text{
+				String: t.Schema,
+				Valid:  t.Schema != "",
+			},
+		})
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == postgres.ErrDuplicateKey {
+				return domain.ErrTenantExists
+			}
+			return errors.Wrap(err, "cannot insert tenant")
+		}
+		stored = tenant(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// withTx runs fn with queries of transaction begun with opts, see postgres.DB.WithTx.
+func (repo *tenantsPostgresRepo) withTx(ctx context.Context, opts pgx.TxOptions, fn func(ctx context.Context, q *db.Queries) error) error {
+	return repo.WithTx(ctx, opts, func(ctx context.Context, tx pgx.Tx) error {
+		return fn(ctx, db.New(tx))
+	})
+}
+
+func (repo *tenantsPostgresRepo) logger(ctx context.Context) *logrus.Entry {
+	return logger.Ctx(ctx, repo.log)
+}
+
+func tenant(row *db.Tenant) *domain.Tenant {
+	return &domain.Tenant{
+		ID:        row.ID,
+		Schema:    row.SchemaName.String,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
+
+func NewTenantsPostgresRepo(db postgres.DB, logger *logrus.Logger) usecase.TenantsRepo {
+	return &tenantsPostgresRepo{
+		DB:  db,
+		log: logger.WithField("layer", "internal.usecase.repo.tenantsPostgresRepo"),
+	}
+}
@@ -0,0 +1,5 @@
+DROP POLICY IF EXISTS books_tenant_isolation ON books;
+ALTER TABLE books NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE books DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS books_tenant_id_idx;
+ALTER TABLE books DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,10 @@
+-- Rows are owned by tenant set for the transaction, rows of requests without tenant have empty tenant_id
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(40) NOT NULL DEFAULT COALESCE(current_setting('app.tenant_id', TRUE), '');
+CREATE INDEX IF NOT EXISTS books_tenant_id_idx ON books(tenant_id);
+ALTER TABLE books ENABLE ROW LEVEL SECURITY;
+-- Table owner is subject to policies too, superusers and roles with BYPASSRLS are not
+ALTER TABLE books FORCE ROW LEVEL SECURITY;
+CREATE POLICY books_tenant_isolation ON books
+    USING (tenant_id = COALESCE(current_setting('app.tenant_id', TRUE), ''))
+    WITH CHECK (tenant_id = COALESCE(current_setting('app.tenant_id', TRUE), ''));
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS tenants(
+    id VARCHAR(40) NOT NULL,
+    schema_name VARCHAR(63) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY(id)
+);
@@ -0,0 +1,2 @@
+DELETE FROM tenants WHERE schema_name IS NULL;
+ALTER TABLE tenants ALTER COLUMN schema_name SET NOT NULL;
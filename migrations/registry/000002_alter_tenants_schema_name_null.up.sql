@@ -0,0 +1,2 @@
+-- Tenants isolated by row level security share application schema
+ALTER TABLE tenants ALTER COLUMN schema_name DROP NOT NULL;
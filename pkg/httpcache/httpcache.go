@@ -0,0 +1,326 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"goapptemplate/pkg/logger"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cache"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HeaderCache = "X-Cache"
+	// CacheBypass is X-Cache value of requests that skipped cache
+	CacheBypass = "bypass"
+
+	keyPrefix    = "httpcache:"
+	genKeyPrefix = keyPrefix + "gen:"
+
+	// localsInvalidated holds time of the last invalidation of requested resource
+	localsInvalidated = "httpcache.invalidated"
+)
+
+// Route is a cached resource, Path is the collection path and
+// Path + "/:id" are its items.
+type Route struct {
+	Path string
+	TTL  time.Duration
+}
+
+type Config struct {
+	// Storage keeps cached responses and generations, must be shared by
+	// all instances for invalidation to be consistent.
+	Storage fiber.Storage
+	// Routes are resources invalidated on writes.
+	Routes []Route
+	// TTL of responses that do not belong to Routes.
+	TTL time.Duration
+	// CacheControl enables client side caching.
+	CacheControl bool
+	// Methods are cacheable safe request methods, GET and HEAD by default.
+	Methods []string
+	// Include are cacheable path prefixes, every path is cacheable when empty.
+	Include []string
+	// Exclude are path prefixes never cached, take precedence over Include.
+	Exclude []string
+	// Vary are request headers cached responses vary by.
+	Vary []string
+	// Principal returns request principal for per-principal caching,
+	// cache is shared between principals when nil.
+	Principal func(c *fiber.Ctx) string
+	// Tenant returns request tenant, cached responses and generations are kept
+	// per tenant when set.
+	Tenant func(c *fiber.Ctx) string
+	// Next skips cache when returns true.
+	Next func(c *fiber.Ctx) bool
+	// Primary reports whether request reads from primary database, responses
+	// read within Settle after invalidation are stored only when it does, so that
+	// responses of lagging read replicas are not cached. Every response is
+	// stored when nil.
+	Primary func(c *fiber.Ctx) bool
+	// Settle is the duration after invalidation replicas may still return
+	// invalidated data, e.g. the duration clients stick to primary after writes.
+	Settle time.Duration
+}
+
+// Cache is a response cache middleware aware of resource keys.
+//
+// Keys consist of tenant, principal, path, sorted query, vary headers and generations.
+// Collection generation is bumped on any successful write to the resource, item
+// generation on writes to the item, so that every cached page and every variant of
+// an item is invalidated at once.
+type Cache struct {
+	config  *Config
+	methods map[string]struct{}
+	cached  fiber.Handler
+	log     *logrus.Entry
+}
+
+// Handler returns fiber middleware.
+func (hc *Cache) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+			err := c.Next()
+			if err != nil {
+				return err
+			}
+			if status := c.Response().StatusCode(); status < 200 || status >= 300 {
+				return nil
+			}
+			err = hc.Invalidate(hc.tenant(c), c.Path())
+			if err != nil {
+				logger.Ctx(c.UserContext(), hc.log).WithError(err).Warn("cannot invalidate cache")
+			}
+			return nil
+		}
+		if _, ok := hc.methods[c.Method()]; !ok {
+			return c.Next()
+		}
+		if hc.bypass(c) {
+			c.Set(HeaderCache, CacheBypass)
+			return c.Next()
+		}
+		if len(hc.config.Vary) > 0 {
+			c.Vary(hc.config.Vary...)
+		}
+		return hc.cached(c)
+	}
+}
+
+// Invalidate bumps generation of item at path and its collection, tenant is empty
+// when cache is not tenant scoped.
+func (hc *Cache) Invalidate(tenant string, path string) error {
+	r, item := hc.match(path)
+	if r == nil {
+		return nil
+	}
+	// Unique value instead of increment avoids read-modify-write races between instances
+	gen := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	if item {
+		// Cached item variants live no longer than route TTL, so does its generation
+		err := hc.config.Storage.Set(genKey(tenant, path), gen, hc.ttl(r))
+		if err != nil {
+			return errors.Wrapf(err, "cannot bump [%s] generation", path)
+		}
+	}
+	err := hc.config.Storage.Set(genKey(tenant, r.Path), gen, 0)
+	if err != nil {
+		return errors.Wrapf(err, "cannot bump [%s] generation", r.Path)
+	}
+	return nil
+}
+
+func (hc *Cache) bypass(c *fiber.Ctx) bool {
+	if hc.config.Next != nil && hc.config.Next(c) {
+		return true
+	}
+	cc := c.Get(fiber.HeaderCacheControl)
+	if strings.Contains(cc, "no-cache") || strings.Contains(cc, "no-store") {
+		return true
+	}
+	path := c.Path()
+	for _, p := range hc.config.Exclude {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	if len(hc.config.Include) == 0 {
+		return false
+	}
+	for _, p := range hc.config.Include {
+		if strings.HasPrefix(path, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (hc *Cache) key(c *fiber.Ctx) string {
+	path := c.Path()
+	tenant := hc.tenant(c)
+	var b strings.Builder
+	b.WriteString(keyPrefix)
+	if tenant != "" {
+		b.WriteString(tenant)
+		b.WriteByte('/')
+	}
+	if hc.config.Principal != nil {
+		b.WriteString(hash(hc.config.Principal(c)))
+		b.WriteByte('|')
+	}
+	b.WriteString(path)
+	b.WriteByte('?')
+	b.WriteString(NormalizeQuery(string(c.Request().URI().QueryString())))
+	if len(hc.config.Vary) > 0 {
+		vary := make([]string, 0, len(hc.config.Vary))
+		for _, h := range hc.config.Vary {
+			vary = append(vary, c.Get(h))
+		}
+		b.WriteByte('|')
+		b.WriteString(hash(strings.Join(vary, "\n")))
+	}
+	r, item := hc.match(path)
+	if r != nil {
+		gen := hc.generation(tenant, r.Path)
+		invalidated := generationTime(gen)
+		b.WriteByte('#')
+		b.WriteString(gen)
+		if item {
+			gen = hc.generation(tenant, path)
+			if t := generationTime(gen); t.After(invalidated) {
+				invalidated = t
+			}
+			b.WriteByte('.')
+			b.WriteString(gen)
+		}
+		c.Locals(localsInvalidated, invalidated)
+	}
+	return b.String()
+}
+
+// unsettled reports whether response must not be stored, as it may be read from
+// a replica which has not replayed the last invalidating write yet.
+func (hc *Cache) unsettled(c *fiber.Ctx) bool {
+	if hc.config.Primary == nil || hc.config.Settle <= 0 || hc.config.Primary(c) {
+		return false
+	}
+	invalidated, _ := c.Locals(localsInvalidated).(time.Time)
+	return time.Since(invalidated) < hc.config.Settle
+}
+
+func (hc *Cache) expiration(c *fiber.Ctx, _ *cache.Config) time.Duration {
+	r, _ := hc.match(c.Path())
+	return hc.ttl(r)
+}
+
+func (hc *Cache) ttl(r *Route) time.Duration {
+	if r != nil && r.TTL > 0 {
+		return r.TTL
+	}
+	return hc.config.TTL
+}
+
+func (hc *Cache) tenant(c *fiber.Ctx) string {
+	if hc.config.Tenant == nil {
+		return ""
+	}
+	return hc.config.Tenant(c)
+}
+
+func (hc *Cache) generation(tenant string, path string) string {
+	gen, err := hc.config.Storage.Get(genKey(tenant, path))
+	if err != nil || gen == nil {
+		return "0"
+	}
+	return string(gen)
+}
+
+// match returns route path belongs to and whether path is the route item.
+func (hc *Cache) match(path string) (*Route, bool) {
+	path = strings.TrimSuffix(path, "/")
+	for i := range hc.config.Routes {
+		r := &hc.config.Routes[i]
+		if path == r.Path {
+			return r, false
+		}
+		id, ok := strings.CutPrefix(path, r.Path+"/")
+		if ok && id != "" && !strings.Contains(id, "/") {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// generationTime returns time generation was bumped at, zero time for the initial
+// generation.
+func generationTime(gen string) time.Time {
+	n, err := strconv.ParseInt(gen, 36, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func genKey(tenant string, path string) string {
+	if tenant == "" {
+		return genKeyPrefix + path
+	}
+	return genKeyPrefix + tenant + "/" + path
+}
+
+// NormalizeQuery sorts query parameters by name and value, so that
+// equivalent queries produce equal keys.
+func NormalizeQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+	for _, v := range values {
+		sort.Strings(v)
+	}
+	// url.Values.Encode sorts by key
+	return values.Encode()
+}
+
+// hash keeps keys short and free of principal and header secrets.
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:12])
+}
+
+func New(config *Config, logger *logrus.Logger) *Cache {
+	hc := &Cache{
+		config:  config,
+		methods: make(map[string]struct{}),
+		log:     logger.WithField("layer", "infrastructure.httpcache.Cache"),
+	}
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = []string{fiber.MethodGet, fiber.MethodHead}
+	}
+	for _, m := range methods {
+		hc.methods[strings.ToUpper(m)] = struct{}{}
+	}
+	hc.cached = cache.New(cache.Config{
+		Storage:     config.Storage,
+		Expiration:  config.TTL,
+		CacheHeader: HeaderCache,
+		// Responses cached per principal must not be stored by shared client side caches
+		CacheControl:        config.CacheControl && config.Principal == nil,
+		KeyGenerator:        hc.key,
+		ExpirationGenerator: hc.expiration,
+		Methods:             methods,
+		// Evaluated after handler, skips storing the response only
+		Next: hc.unsettled,
+	})
+	return hc
+}
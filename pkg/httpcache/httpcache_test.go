@@ -0,0 +1,89 @@
+package httpcache
+
+import (
+	"goapptemplate/pkg/lru"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// headerPrimary marks test requests reading from primary
+const headerPrimary = "X-Primary"
+
+func newTestApp(t *testing.T, settle time.Duration) *fiber.App {
+	t.Helper()
+	storage := lru.New(0, time.Minute)
+	t.Cleanup(func() { _ = storage.Close() })
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	hc := New(&Config{
+		Storage: storage,
+		Routes:  []Route{{Path: "/books", TTL: time.Minute}},
+		TTL:     time.Minute,
+		Primary: func(c *fiber.Ctx) bool {
+			return c.Get(headerPrimary) != ""
+		},
+		Settle: settle,
+	}, l)
+	app := fiber.New()
+	app.Use(hc.Handler())
+	app.Get("/books/:id", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+	app.Put("/books/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	return app
+}
+
+func testRequest(t *testing.T, app *fiber.App, method string, primary bool) string {
+	t.Helper()
+	req := httptest.NewRequest(method, "/books/1", nil)
+	if primary {
+		req.Header.Set(headerPrimary, "1")
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s error = %v", method, err)
+	}
+	return res.Header.Get(HeaderCache)
+}
+
+func TestCacheSettle(t *testing.T) {
+	tests := []struct {
+		name    string
+		settle  time.Duration
+		primary bool
+		// want are X-Cache values of two reads after write
+		want [2]string
+	}{
+		{name: "replica read within settle", settle: time.Minute, want: [2]string{"unreachable", "unreachable"}},
+		{name: "primary read within settle", settle: time.Minute, primary: true, want: [2]string{"miss", "hit"}},
+		{name: "without settle", want: [2]string{"miss", "hit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, tt.settle)
+			testRequest(t, app, fiber.MethodPut, false)
+			for i, want := range tt.want {
+				if got := testRequest(t, app, fiber.MethodGet, tt.primary); got != want {
+					t.Fatalf("read %d %s = %q, want %q", i+1, HeaderCache, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheSettleWithoutWrite(t *testing.T) {
+	app := newTestApp(t, time.Minute)
+	// Resource never invalidated is settled
+	for i, want := range []string{"miss", "hit"} {
+		if got := testRequest(t, app, fiber.MethodGet, false); got != want {
+			t.Fatalf("read %d %s = %q, want %q", i+1, HeaderCache, got, want)
+		}
+	}
+}
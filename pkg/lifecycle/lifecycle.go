@@ -0,0 +1,141 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a component start and stop callbacks, both are optional.
+type Hook struct {
+	Name    string
+	OnStart func(context.Context) error
+	OnStop  func(context.Context) error
+}
+
+// Lifecycle starts components in the order they were appended and stops them in reverse.
+//
+// Components are expected to be appended after their dependencies, so that
+// dependants are stopped first and dependencies last.
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	errs    chan error
+	wg      sync.WaitGroup
+	log     *logrus.Entry
+}
+
+// Append registers component hook.
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, h)
+}
+
+// Start runs OnStart hooks in order, already started components are stopped
+// when one of them fails.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, h := range l.hooks[l.started:] {
+		if h.OnStart != nil {
+			l.log.WithField("component", h.Name).Debug("starting")
+			err := h.OnStart(ctx)
+			if err != nil {
+				// Logged before logger itself may be stopped
+				l.log.WithError(err).WithField("component", h.Name).Error("cannot start")
+				stopErr := l.stop(ctx)
+				return errors.Join(fmt.Errorf("cannot start [%s]: %w", h.Name, err), stopErr)
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs OnStop hooks of started components in reverse order and waits for
+// background goroutines, all errors are collected.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop(ctx)
+}
+
+// Abort stops components when setup fails before Start completes. Along with
+// started components, components without OnStart are stopped, as they run once
+// created. Components not started are dropped.
+func (l *Lifecycle) Abort(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for i := len(l.hooks) - 1; i >= l.started; i-- {
+		h := l.hooks[i]
+		if h.OnStart != nil || h.OnStop == nil {
+			continue
+		}
+		l.log.WithField("component", h.Name).Debug("stopping")
+		err := h.OnStop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot stop [%s]: %w", h.Name, err))
+		}
+	}
+	l.hooks = l.hooks[:l.started]
+	return errors.Join(append(errs, l.stop(ctx))...)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.OnStop == nil {
+			continue
+		}
+		l.log.WithField("component", h.Name).Debug("stopping")
+		err := h.OnStop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot stop [%s]: %w", h.Name, err))
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("cannot wait for background work: %w", ctx.Err()))
+	}
+	return errors.Join(errs...)
+}
+
+// Go runs fn in background, its error is propagated to Err.
+func (l *Lifecycle) Go(name string, fn func() error) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		err := fn()
+		if err != nil {
+			select {
+			case l.errs <- fmt.Errorf("[%s] failed: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// Err reports the first failure of background goroutines.
+func (l *Lifecycle) Err() <-chan error {
+	return l.errs
+}
+
+func New(logger *logrus.Logger) *Lifecycle {
+	return &Lifecycle{
+		errs: make(chan error, 1),
+		log:  logger.WithField("layer", "infrastructure.lifecycle.Lifecycle"),
+	}
+}
@@ -0,0 +1,73 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLifecycle() *Lifecycle {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return New(l)
+}
+
+func TestAbort(t *testing.T) {
+	lc := newTestLifecycle()
+	var stopped []string
+	stop := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			stopped = append(stopped, name)
+			return err
+		}
+	}
+	errStop := errors.New("stop failed")
+	lc.Append(Hook{Name: "logger", OnStop: stop("logger", nil)})
+	lc.Append(Hook{Name: "postgres", OnStop: stop("postgres", errStop)})
+	lc.Append(Hook{
+		Name:    "http",
+		OnStart: func(context.Context) error { return nil },
+		OnStop:  stop("http", nil),
+	})
+	lc.Append(Hook{Name: "storage", OnStop: stop("storage", nil)})
+
+	err := lc.Abort(context.Background())
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Abort() error = %v, want %v", err, errStop)
+	}
+	// Not started http is not stopped
+	want := []string{"storage", "postgres", "logger"}
+	if !reflect.DeepEqual(stopped, want) {
+		t.Fatalf("Abort() stopped = %v, want %v", stopped, want)
+	}
+	stopped = nil
+	if err := lc.Stop(context.Background()); err != nil || stopped != nil {
+		t.Fatalf("Stop() after Abort() error = %v, stopped = %v, want nothing", err, stopped)
+	}
+}
+
+func TestAbortAfterStart(t *testing.T) {
+	lc := newTestLifecycle()
+	var stopped []string
+	lc.Append(Hook{
+		Name:    "relay",
+		OnStart: func(context.Context) error { return nil },
+		OnStop: func(context.Context) error {
+			stopped = append(stopped, "relay")
+			return nil
+		},
+	})
+	if err := lc.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := lc.Abort(context.Background()); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+	if !reflect.DeepEqual(stopped, []string{"relay"}) {
+		t.Fatalf("Abort() stopped = %v, want [relay]", stopped)
+	}
+}
@@ -0,0 +1,133 @@
+package listener
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+)
+
+const (
+	NetworkTCP  = "tcp"
+	NetworkTCP4 = "tcp4"
+	NetworkTCP6 = "tcp6"
+	NetworkUnix = "unix"
+	// NetworkSystemd uses socket passed by systemd socket activation
+	NetworkSystemd = "systemd"
+)
+
+type Config struct {
+	// Network is [tcp|tcp4|tcp6|unix|systemd].
+	Network string
+	// Address is host:port for tcp, socket path for unix and socket
+	// FileDescriptorName for systemd, the first passed socket is used when empty.
+	Address string
+	// TLS is used to serve HTTPS, plain connections are served when nil.
+	TLS *tls.Config
+	// Prefix is the path prefix routes are served under.
+	Prefix string
+}
+
+// Listener accepts connections aware of the listener they were accepted by.
+type Listener struct {
+	net.Listener
+	config *Config
+}
+
+// Accept implements net.Listener.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if l.config.TLS != nil {
+		return &tlsConn{Conn: tls.Server(c, l.config.TLS), listener: l}, nil
+	}
+	return &conn{Conn: c, listener: l}, nil
+}
+
+// Config returns listener config.
+func (l *Listener) Config() *Config {
+	return l.config
+}
+
+// String describes listener for logs.
+func (l *Listener) String() string {
+	scheme := "http"
+	if l.config.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s+%s://%s%s", scheme, l.Addr().Network(), l.Addr().String(), l.config.Prefix)
+}
+
+type conn struct {
+	net.Conn
+	listener *Listener
+}
+
+// tlsConn keeps *tls.Conn methods, so that servers detect TLS connections.
+type tlsConn struct {
+	*tls.Conn
+	listener *Listener
+}
+
+// FromConn returns listener connection was accepted by.
+func FromConn(c net.Conn) (*Listener, bool) {
+	switch c := c.(type) {
+	case *conn:
+		return c.listener, true
+	case *tlsConn:
+		return c.listener, true
+	default:
+		return nil, false
+	}
+}
+
+// Listen creates listener.
+func Listen(config *Config) (*Listener, error) {
+	var ln net.Listener
+	var err error
+	switch config.Network {
+	case NetworkTCP, NetworkTCP4, NetworkTCP6:
+		ln, err = net.Listen(config.Network, config.Address)
+	case NetworkUnix:
+		// Socket left by a crashed process would prevent listening
+		if fi, statErr := os.Stat(config.Address); statErr == nil && fi.Mode().Type() == fs.ModeSocket {
+			err = os.Remove(config.Address)
+			if err != nil {
+				return nil, fmt.Errorf("cannot remove stale socket [%s]: %w", config.Address, err)
+			}
+		}
+		ln, err = net.Listen(config.Network, config.Address)
+	case NetworkSystemd:
+		ln, err = systemdListener(config.Address)
+	default:
+		return nil, fmt.Errorf("unknown network [%s]", config.Network)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen [%s] [%s]: %w", config.Network, config.Address, err)
+	}
+	return &Listener{
+		Listener: ln,
+		config:   config,
+	}, nil
+}
+
+// ListenAll creates listeners, already created listeners are closed when one of them fails.
+func ListenAll(configs []*Config) ([]*Listener, error) {
+	listeners := make([]*Listener, 0, len(configs))
+	for _, c := range configs {
+		l, err := Listen(c)
+		if err != nil {
+			errs := []error{err}
+			for _, l := range listeners {
+				errs = append(errs, l.Close())
+			}
+			return nil, errors.Join(errs...)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
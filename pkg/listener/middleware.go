@@ -0,0 +1,28 @@
+package listener
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StripPrefix makes routes of listeners with prefix served under it,
+// requests outside of listener prefix are not found.
+func StripPrefix() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		l, ok := FromConn(c.Context().Conn())
+		if !ok || l.config.Prefix == "" {
+			return c.Next()
+		}
+		path, ok := strings.CutPrefix(c.Path(), l.config.Prefix)
+		if !ok || (path != "" && path[0] != '/') {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.ErrNotFound)
+		}
+		if path == "" {
+			path = "/"
+		}
+		// Subsequent handlers are matched against stripped path
+		c.Path(path)
+		return c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemdListenFdsStart is the first file descriptor passed by systemd (SD_LISTEN_FDS_START)
+const systemdListenFdsStart = 3
+
+var systemd struct {
+	once      sync.Once
+	listeners []net.Listener
+	names     []string
+	err       error
+}
+
+// systemdListener returns socket passed by systemd socket activation by its
+// FileDescriptorName, the first socket is returned when name is empty.
+func systemdListener(name string) (net.Listener, error) {
+	systemd.once.Do(func() {
+		systemd.listeners, systemd.names, systemd.err = systemdListeners()
+	})
+	if systemd.err != nil {
+		return nil, systemd.err
+	}
+	for i, l := range systemd.listeners {
+		if name == "" || systemd.names[i] == name {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("socket [%s] is not passed by systemd", name)
+}
+
+// systemdListeners wraps sockets passed according to sd_listen_fds(3),
+// file descriptors can only be wrapped once.
+func systemdListeners() ([]net.Listener, []string, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, fmt.Errorf("sockets are not passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil, fmt.Errorf("invalid LISTEN_FDS [%s]", os.Getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make([]net.Listener, 0, n)
+	fdNames := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(systemdListenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		// FileListener duplicates descriptor
+		_ = f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot use systemd socket [%d]: %w", systemdListenFdsStart+i, err)
+		}
+		listeners = append(listeners, l)
+		fdNames = append(fdNames, name)
+	}
+	return listeners, fdNames, nil
+}
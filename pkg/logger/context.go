@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Request scoped entry fields.
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldMethod    = "method"
+	FieldRoute     = "route"
+	FieldPath      = "path"
+	FieldUser      = "user"
+	FieldTenant    = "tenant_id"
+)
+
+type ctxKey struct{}
+
+// WithEntry returns a copy of ctx carrying entry.
+func WithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, entry)
+}
+
+// FromContext returns entry stored in ctx, if any.
+func FromContext(ctx context.Context) (*logrus.Entry, bool) {
+	entry, ok := ctx.Value(ctxKey{}).(*logrus.Entry)
+	return entry, ok
+}
+
+// Ctx returns entry stored in ctx enriched with base fields (layer, etc.),
+// base is returned as is when ctx has no entry.
+func Ctx(ctx context.Context, base *logrus.Entry) *logrus.Entry {
+	entry, ok := FromContext(ctx)
+	if !ok {
+		return base
+	}
+	return entry.WithFields(base.Data)
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Error reporting entry fields.
+const (
+	FieldStack      = "stack"
+	FieldSuppressed = "suppressed"
+)
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// ErrorStack attaches the deepest pkg/errors stack trace of entry error
+// to the entry. Implements Processor.
+func ErrorStack(entry *logrus.Entry) {
+	if entry.Level > logrus.ErrorLevel {
+		return
+	}
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok {
+		return
+	}
+	var st stackTracer
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if s, ok := e.(stackTracer); ok {
+			st = s
+		}
+	}
+	if st != nil {
+		entry.Data[FieldStack] = strings.TrimSpace(fmt.Sprintf("%+v", st.StackTrace()))
+	}
+}
+
+// Redactor masks values of sensitive entry fields.
+type Redactor struct {
+	fields map[string]struct{}
+	mask   string
+}
+
+// Redact implements Processor.
+func (r *Redactor) Redact(entry *logrus.Entry) {
+	for k := range entry.Data {
+		if _, ok := r.fields[strings.ToLower(k)]; ok {
+			entry.Data[k] = r.mask
+		}
+	}
+}
+
+// NewRedactor creates redactor that matches field names case-insensitively.
+func NewRedactor(fields []string, mask string) *Redactor {
+	r := &Redactor{
+		fields: make(map[string]struct{}, len(fields)),
+		mask:   mask,
+	}
+	for _, f := range fields {
+		r.fields[strings.ToLower(strings.TrimSpace(f))] = struct{}{}
+	}
+	return r
+}
+
+type rateLimitState struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// RateLimiter suppresses repeated identical warning and error entries.
+//
+// Entries are identical when they share level, layer, message and error text.
+// At most burst of them are written per window, the first entry written after
+// suppression carries the number of suppressed ones.
+type RateLimiter struct {
+	mu     sync.Mutex
+	window time.Duration
+	burst  int
+	states map[string]*rateLimitState
+}
+
+// Allow implements Filter.
+func (rl *RateLimiter) Allow(entry *logrus.Entry) bool {
+	if entry.Level > logrus.WarnLevel || entry.Level <= logrus.FatalLevel {
+		return true
+	}
+	key := fmt.Sprintf("%s|%v|%s|%v", entry.Level, entry.Data[FieldLayer], entry.Message, entry.Data[logrus.ErrorKey])
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	st, ok := rl.states[key]
+	if !ok || now.Sub(st.start) >= rl.window {
+		if ok && st.suppressed > 0 {
+			entry.Data[FieldSuppressed] = st.suppressed
+		}
+		rl.states[key] = &rateLimitState{start: now, count: 1}
+		rl.cleanup(now)
+		return true
+	}
+	if st.count < rl.burst {
+		st.count++
+		return true
+	}
+	st.suppressed++
+	return false
+}
+
+// cleanup forgets states of expired windows without suppressed entries.
+func (rl *RateLimiter) cleanup(now time.Time) {
+	for k, st := range rl.states {
+		if now.Sub(st.start) >= rl.window && st.suppressed == 0 {
+			delete(rl.states, k)
+		}
+	}
+}
+
+func NewRateLimiter(window time.Duration, burst int) *RateLimiter {
+	return &RateLimiter{
+		window: window,
+		burst:  burst,
+		states: make(map[string]*rateLimitState),
+	}
+}
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
+)
+
+// NewRotatingFile creates writer that rotates log files every rotationTime
+// and removes files older than maxAge.
+//
+// Files are named <dir>/<name>.<YYYYmmddHHMM>.log, <dir>/<name>.log always links to the current one.
+func NewRotatingFile(dir string, name string, maxAge time.Duration, rotationTime time.Duration) (io.WriteCloser, error) {
+	w, err := rotatelogs.New(
+		filepath.Join(dir, fmt.Sprintf("%s.%%Y%%m%%d%%H%%M.log", name)),
+		rotatelogs.WithLinkName(filepath.Join(dir, name+".log")),
+		rotatelogs.WithMaxAge(maxAge),
+		rotatelogs.WithRotationTime(rotationTime),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create rotating log file [%s] in [%s]", name, dir)
+	}
+	return w, nil
+}
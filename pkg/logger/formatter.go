@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrFormat = errors.New("unknown log format")
+
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Filter decides whether entry should be written.
+type Filter func(*logrus.Entry) bool
+
+// Processor enriches, modifies or forwards entry that passed filters.
+type Processor func(*logrus.Entry)
+
+// Formatter wraps logrus.Formatter with entry filters, processors and field renames.
+//
+// Unlike logrus hooks, filters are able to drop entries, which is why the whole
+// entry pipeline lives here.
+type Formatter struct {
+	logrus.Formatter
+	// Filters are applied in order, first one to return false drops the entry.
+	Filters []Filter
+	// Processors are applied in order to entries that passed filters.
+	Processors []Processor
+	// Fields maps entry data fields to names expected by log shippers.
+	Fields map[string]string
+}
+
+// Format implements logrus.Formatter.
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	for _, filter := range f.Filters {
+		if !filter(entry) {
+			return nil, nil
+		}
+	}
+	for _, process := range f.Processors {
+		process(entry)
+	}
+	for from, to := range f.Fields {
+		if v, ok := entry.Data[from]; ok {
+			delete(entry.Data, from)
+			entry.Data[to] = v
+		}
+	}
+	return f.Formatter.Format(entry)
+}
+
+// NewFormatter creates formatter of type [text|json|logfmt].
+//
+// Built-in logrus field names (time, level, msg, func, file, logrus_error)
+// found in fields are passed as logrus.FieldMap, the rest are renamed in entry data.
+func NewFormatter(format string, pretty bool, fields map[string]string) (*Formatter, error) {
+	fieldMap := logrus.FieldMap{}
+	dataFields := map[string]string{}
+	for from, to := range fields {
+		switch from {
+		case logrus.FieldKeyTime:
+			fieldMap[logrus.FieldKeyTime] = to
+		case logrus.FieldKeyLevel:
+			fieldMap[logrus.FieldKeyLevel] = to
+		case logrus.FieldKeyMsg:
+			fieldMap[logrus.FieldKeyMsg] = to
+		case logrus.FieldKeyFunc:
+			fieldMap[logrus.FieldKeyFunc] = to
+		case logrus.FieldKeyFile:
+			fieldMap[logrus.FieldKeyFile] = to
+		case logrus.FieldKeyLogrusError:
+			fieldMap[logrus.FieldKeyLogrusError] = to
+		default:
+			dataFields[from] = to
+		}
+	}
+	var f logrus.Formatter
+	switch format {
+	case FormatText, "":
+		f = &logrus.TextFormatter{
+			FullTimestamp:          true,
+			DisableLevelTruncation: true,
+			PadLevelText:           true,
+			QuoteEmptyFields:       true,
+			FieldMap:               fieldMap,
+		}
+	case FormatLogfmt:
+		f = &logrus.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			QuoteEmptyFields: true,
+			FieldMap:         fieldMap,
+		}
+	case FormatJSON:
+		f = &logrus.JSONFormatter{
+			PrettyPrint: pretty,
+			FieldMap:    fieldMap,
+		}
+	default:
+		return nil, ErrFormat
+	}
+	return &Formatter{
+		Formatter: f,
+		Fields:    dataFields,
+	}, nil
+}
@@ -0,0 +1,22 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// RenameHook renames entry data fields before they reach formatter.
+type RenameHook map[string]string
+
+// Fire implements logrus.Hook.
+func (h RenameHook) Fire(entry *logrus.Entry) error {
+	for from, to := range h {
+		if v, ok := entry.Data[from]; ok {
+			delete(entry.Data, from)
+			entry.Data[to] = v
+		}
+	}
+	return nil
+}
+
+// Levels implements logrus.Hook.
+func (h RenameHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FieldLayer is the entry field used to distinguish application layers.
+const FieldLayer = "layer"
+
+// Levels controls global and per-layer logrus levels at runtime.
+//
+// Logger level is kept at the most verbose of configured levels, entries are
+// then filtered by their layer level with Levels.Enabled.
+type Levels struct {
+	mu     sync.RWMutex
+	logger *logrus.Logger
+	level  logrus.Level
+	layers map[string]logrus.Level
+}
+
+// Level returns global level.
+func (l *Levels) Level() logrus.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.level
+}
+
+// Layers returns a copy of per-layer levels.
+func (l *Levels) Layers() map[string]logrus.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	layers := make(map[string]logrus.Level, len(l.layers))
+	for k, v := range l.layers {
+		layers[k] = v
+	}
+	return layers
+}
+
+// SetLevel sets global level.
+func (l *Levels) SetLevel(lvl logrus.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = lvl
+	l.apply()
+}
+
+// SetLayerLevel sets level for a single layer.
+func (l *Levels) SetLayerLevel(layer string, lvl logrus.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.layers[layer] = lvl
+	l.apply()
+}
+
+// UnsetLayerLevel makes layer fallback to global level.
+func (l *Levels) UnsetLayerLevel(layer string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.layers, layer)
+	l.apply()
+}
+
+// Set replaces global and all per-layer levels at once.
+func (l *Levels) Set(lvl logrus.Level, layers map[string]logrus.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = lvl
+	l.layers = make(map[string]logrus.Level, len(layers))
+	for k, v := range layers {
+		l.layers[k] = v
+	}
+	l.apply()
+}
+
+// Enabled reports whether entry passes its layer level. Implements Filter.
+func (l *Levels) Enabled(entry *logrus.Entry) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	lvl := l.level
+	if layer, ok := entry.Data[FieldLayer].(string); ok {
+		if v, ok := l.layers[layer]; ok {
+			lvl = v
+		}
+	}
+	return lvl >= entry.Level
+}
+
+func (l *Levels) apply() {
+	max := l.level
+	for _, v := range l.layers {
+		if v > max {
+			max = v
+		}
+	}
+	l.logger.SetLevel(max)
+}
+
+func NewLevels(logger *logrus.Logger, level logrus.Level, layers map[string]logrus.Level) *Levels {
+	l := &Levels{
+		logger: logger,
+	}
+	l.Set(level, layers)
+	return l
+}
+
+// ParseLayers parses per-layer level names.
+func ParseLayers(layers map[string]string) (map[string]logrus.Level, error) {
+	m := make(map[string]logrus.Level, len(layers))
+	for k, v := range layers {
+		lvl, err := logrus.ParseLevel(v)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = lvl
+	}
+	return m, nil
+}
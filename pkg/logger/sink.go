@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SinkNone = "none"
+	SinkFile = "file"
+	SinkHTTP = "http"
+)
+
+// Event is an error report sent to Sink.
+type Event struct {
+	ID        string                 `json:"event_id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Logger    string                 `json:"logger,omitempty"`
+	Message   string                 `json:"message"`
+	Exception *EventException        `json:"exception,omitempty"`
+	Extra     map[string]interface{} `json:"extra,omitempty"`
+}
+
+type EventException struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace string `json:"stacktrace,omitempty"`
+}
+
+// Sink receives error reports.
+type Sink interface {
+	Send(ctx context.Context, event *Event) error
+	Close() error
+}
+
+// NewEvent converts entry to Event.
+func NewEvent(entry *logrus.Entry) *Event {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	e := &Event{
+		ID:        hex.EncodeToString(id),
+		Timestamp: entry.Time,
+		Level:     entry.Level.String(),
+		Message:   entry.Message,
+		Extra:     make(map[string]interface{}, len(entry.Data)),
+	}
+	for k, v := range entry.Data {
+		switch k {
+		case FieldLayer:
+			e.Logger = fmt.Sprint(v)
+		case logrus.ErrorKey, FieldStack:
+		default:
+			e.Extra[k] = v
+		}
+	}
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		e.Exception = &EventException{
+			Type:  fmt.Sprintf("%T", errors.Cause(err)),
+			Value: err.Error(),
+		}
+		if st, ok := entry.Data[FieldStack].(string); ok {
+			e.Exception.Stacktrace = st
+		}
+	}
+	return e
+}
+
+// AsyncSink forwards entries to Sink in background, so that slow sinks never block logging.
+//
+// Events are dropped when buffer is full.
+type AsyncSink struct {
+	sink    Sink
+	level   logrus.Level
+	timeout time.Duration
+	events  chan *Event
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// Process implements Processor.
+func (s *AsyncSink) Process(entry *logrus.Entry) {
+	if entry.Level > s.level {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- NewEvent(entry):
+	default:
+		fmt.Fprintln(os.Stderr, "logger: error sink buffer is full, event dropped")
+	}
+}
+
+// Close flushes buffered events and closes underlying sink.
+func (s *AsyncSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return s.sink.Close()
+}
+
+func (s *AsyncSink) run() {
+	defer s.wg.Done()
+	for e := range s.events {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		err := s.sink.Send(ctx, e)
+		cancel()
+		if err != nil {
+			// Not logged with logrus to avoid feedback loop
+			fmt.Fprintf(os.Stderr, "logger: cannot send event [%s] to error sink: %s\n", e.ID, err)
+		}
+	}
+}
+
+// NewAsyncSink creates sink processor for entries of level or more severe.
+func NewAsyncSink(sink Sink, level logrus.Level, buffer int, timeout time.Duration) *AsyncSink {
+	s := &AsyncSink{
+		sink:    sink,
+		level:   level,
+		timeout: timeout,
+		events:  make(chan *Event, buffer),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+type fileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Send implements Sink.
+func (s *fileSink) Send(ctx context.Context, event *Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal event")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(b, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "cannot write event")
+	}
+	return nil
+}
+
+// Close implements Sink.
+func (s *fileSink) Close() error {
+	return s.f.Close()
+}
+
+// NewFileSink creates sink appending events as JSON lines to file.
+func NewFileSink(filepath string) (Sink, error) {
+	f, err := os.OpenFile(filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open error sink file [%s]", filepath)
+	}
+	return &fileSink{f: f}, nil
+}
+
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// Send implements Sink.
+func (s *httpSink) Send(ctx context.Context, event *Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal event")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "cannot create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "cannot send request")
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status [%s]", res.Status)
+	}
+	return nil
+}
+
+// Close implements Sink.
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// NewHTTPSink creates sink posting events as JSON to url, payload is
+// compatible with Sentry-like event store endpoints.
+func NewHTTPSink(url string) Sink {
+	return &httpSink{
+		url:    url,
+		client: &http.Client{},
+	}
+}
@@ -0,0 +1,153 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key string
+	val []byte
+	// exp is unix nano expiration time, 0 means no expiration
+	exp int64
+}
+
+// Storage is a bounded in-process least recently used storage.
+// Implements fiber.Storage.
+type Storage struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	done       chan struct{}
+	once       sync.Once
+}
+
+// Get value by key.
+func (s *Storage) Get(key string) ([]byte, error) {
+	if len(key) <= 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	e := el.Value.(*entry)
+	if e.exp != 0 && time.Now().UnixNano() >= e.exp {
+		s.remove(el)
+		return nil, nil
+	}
+	s.ll.MoveToFront(el)
+	return e.val, nil
+}
+
+// Set key with value, zero exp means no expiration.
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) <= 0 || len(val) <= 0 {
+		return nil
+	}
+	var e int64
+	if exp > 0 {
+		e = time.Now().Add(exp).UnixNano()
+	}
+	// Values are copied, callers are free to reuse their buffers
+	v := make([]byte, len(val))
+	copy(v, val)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.ll.MoveToFront(el)
+		el.Value.(*entry).val = v
+		el.Value.(*entry).exp = e
+		return nil
+	}
+	s.items[key] = s.ll.PushFront(&entry{key: key, val: v, exp: e})
+	for s.maxEntries > 0 && s.ll.Len() > s.maxEntries {
+		s.remove(s.ll.Back())
+	}
+	return nil
+}
+
+// Delete key by key.
+func (s *Storage) Delete(key string) error {
+	if len(key) <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+// Reset all keys.
+func (s *Storage) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ll.Init()
+	s.items = make(map[string]*list.Element)
+	return nil
+}
+
+// Close stops expired entries collection.
+func (s *Storage) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	return nil
+}
+
+// Len returns number of stored entries, including expired but not yet collected.
+func (s *Storage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+func (s *Storage) remove(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.items, el.Value.(*entry).key)
+}
+
+func (s *Storage) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			s.mu.Lock()
+			for el := s.ll.Back(); el != nil; {
+				prev := el.Prev()
+				if e := el.Value.(*entry); e.exp != 0 && now >= e.exp {
+					s.remove(el)
+				}
+				el = prev
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// New creates storage holding at most maxEntries (unbounded when 0),
+// expired entries are collected every gcInterval, or only when accessed
+// when gcInterval is not positive.
+func New(maxEntries int, gcInterval time.Duration) *Storage {
+	s := &Storage{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		done:       make(chan struct{}),
+	}
+	if gcInterval > 0 {
+		go s.gc(gcInterval)
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"goapptemplate/pkg/postgres"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const checksumTableSuffix = "_migrations_checksums"
+
+// ChecksumMismatch is an applied migration which source has changed since it was applied.
+type ChecksumMismatch struct {
+	Version    uint   `json:"version" yaml:"version"`
+	Identifier string `json:"identifier" yaml:"identifier"`
+	Applied    string `json:"applied" yaml:"applied"`
+	Source     string `json:"source" yaml:"source"`
+}
+
+func (cm ChecksumMismatch) String() string {
+	return fmt.Sprintf("migration [%d] [%s] is changed since applied", cm.Version, cm.Identifier)
+}
+
+// Verify compares checksums recorded when migrations were applied with source.
+// Versions missing in source (e.g. applied by a newer release) are skipped.
+func (pm *PostgresMigrator) Verify(ctx context.Context) ([]ChecksumMismatch, error) {
+	conn, err := postgres.NewConn(ctx, pm.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create connection")
+	}
+	defer conn.Close(context.Background())
+	var exists bool
+	err = conn.QueryRow(ctx, "select to_regclass($1) is not null", pm.checksumTable()).Scan(&exists)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot check checksum table")
+	}
+	if !exists {
+		return nil, nil
+	}
+	rows, err := conn.Query(ctx, fmt.Sprintf("select version, checksum from %s order by version", pm.checksumTable()))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot query checksums")
+	}
+	applied := make(map[uint]string)
+	var order []uint
+	for rows.Next() {
+		var v int64
+		var sum string
+		err = rows.Scan(&v, &sum)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot scan checksum")
+		}
+		applied[uint(v)] = sum
+		order = append(order, uint(v))
+	}
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "cannot query checksums")
+	}
+	var mismatches []ChecksumMismatch
+	for _, v := range order {
+		m, ok, err := pm.readUp(v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		sum := checksum(m.SQL)
+		if sum != applied[v] {
+			mismatches = append(mismatches, ChecksumMismatch{
+				Version:    v,
+				Identifier: m.Identifier,
+				Applied:    applied[v],
+				Source:     sum,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// recordChecksums stores checksums of applied migrations which are not recorded yet
+// and removes checksums of reverted ones. Checksums of migrations applied before
+// they were recorded are taken from current source.
+func (pm *PostgresMigrator) recordChecksums(ctx context.Context, conn *pgx.Conn) error {
+	table := pm.checksumTable()
+	_, err := conn.Exec(ctx, fmt.Sprintf(
+		"create table if not exists %s (version bigint primary key, identifier text not null, checksum text not null, applied_at timestamptz not null default now())",
+		table,
+	))
+	if err != nil {
+		return errors.Wrap(err, "cannot create checksum table")
+	}
+	applied, err := pm.applied()
+	if err != nil {
+		return err
+	}
+	current := int64(-1)
+	if len(applied) > 0 {
+		current = int64(applied[len(applied)-1].Version)
+	}
+	_, err = conn.Exec(ctx, fmt.Sprintf("delete from %s where version > $1", table), current)
+	if err != nil {
+		return errors.Wrap(err, "cannot delete reverted checksums")
+	}
+	for _, m := range applied {
+		_, err = conn.Exec(
+			ctx,
+			fmt.Sprintf("insert into %s (version, identifier, checksum) values ($1, $2, $3) on conflict (version) do nothing", table),
+			int64(m.Version),
+			m.Identifier,
+			checksum(m.SQL),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "cannot record migration [%d] checksum", m.Version)
+		}
+	}
+	return nil
+}
+
+// applied returns up migrations of applied versions in order.
+func (pm *PostgresMigrator) applied() ([]Migration, error) {
+	current, dirty, err := pm.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read [%s] version", pm.schema)
+	}
+	if dirty {
+		return nil, pm.dirtyError(migrate.ErrDirty{Version: int(current)})
+	}
+	versions, err := pm.Versions()
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, v := range versions {
+		if v > current {
+			break
+		}
+		m, ok, err := pm.readUp(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+func (pm *PostgresMigrator) checksumTable() string {
+	return pgx.Identifier{pm.schema, pm.schema + checksumTableSuffix}.Sanitize()
+}
+
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"goapptemplate/pkg/postgres"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// Kinds of compared schema objects
+const (
+	DriftTable  = "table"
+	DriftColumn = "column"
+	DriftIndex  = "index"
+)
+
+const driftSchemaSuffix = "_drift"
+
+var driftKinds = []string{DriftTable, DriftColumn, DriftIndex}
+
+var driftQueries = map[string]string{
+	DriftTable: `select table_name, ''
+		from information_schema.tables
+		where table_schema = $1 and table_type = 'BASE TABLE' and table_name <> all($2)`,
+	DriftColumn: `select table_name || '.' || column_name,
+			data_type || case when is_nullable = 'NO' then ' not null' else '' end || coalesce(' default ' || column_default, '')
+		from information_schema.columns
+		where table_schema = $1 and table_name <> all($2)`,
+	DriftIndex: `select indexname, indexdef
+		from pg_indexes
+		where schemaname = $1 and tablename <> all($2)`,
+}
+
+// Drift is a schema object which differs from the one produced by migrations,
+// Expected is empty for unexpected objects and Actual is empty for missing ones.
+type Drift struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Name     string `json:"name" yaml:"name"`
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+}
+
+func (d Drift) String() string {
+	switch {
+	case d.Actual == "":
+		return fmt.Sprintf("%s [%s] is missing", d.Kind, d.Name)
+	case d.Expected == "":
+		return fmt.Sprintf("%s [%s] is unexpected", d.Kind, d.Name)
+	default:
+		return fmt.Sprintf("%s [%s] is [%s], expected [%s]", d.Kind, d.Name, d.Actual, d.Expected)
+	}
+}
+
+// Drift compares tables, columns and indexes of schema with the ones applied migrations
+// produce in a scratch schema. Scratch schema is created in a transaction which
+// is rolled back.
+func (pm *PostgresMigrator) Drift(ctx context.Context) ([]Drift, error) {
+	applied, err := pm.applied()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := postgres.NewConn(ctx, pm.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create connection")
+	}
+	defer conn.Close(context.Background())
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot begin transaction")
+	}
+	defer tx.Rollback(context.Background())
+	scratch := pm.schema + driftSchemaSuffix
+	_, err = tx.Exec(ctx, fmt.Sprintf("create schema %s", pgx.Identifier{scratch}.Sanitize()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create scratch schema [%s]", scratch)
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("set local search_path to %s", pgx.Identifier{scratch}.Sanitize()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot set search path to [%s]", scratch)
+	}
+	for _, m := range applied {
+		_, err = tx.Exec(ctx, m.SQL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot execute migration [%d] in scratch schema", m.Version)
+		}
+	}
+	excluded := []string{pm.schema + "_migrations", pm.schema + checksumTableSuffix}
+	var drifts []Drift
+	for _, kind := range driftKinds {
+		expected, err := catalog(ctx, tx, kind, scratch, excluded)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := catalog(ctx, tx, kind, pm.schema, excluded)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, compare(kind, expected, actual)...)
+	}
+	return drifts, nil
+}
+
+// catalog returns definitions of schema objects of kind by name, schema name is
+// removed from definitions so that schemas can be compared.
+func catalog(ctx context.Context, tx pgx.Tx, kind string, schema string, excluded []string) (map[string]string, error) {
+	rows, err := tx.Query(ctx, driftQueries[kind], schema, excluded)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot query [%s] %ss", schema, kind)
+	}
+	defer rows.Close()
+	objects := make(map[string]string)
+	for rows.Next() {
+		var name, def string
+		err = rows.Scan(&name, &def)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot scan [%s] %s", schema, kind)
+		}
+		objects[name] = strings.ReplaceAll(def, schema+".", "")
+	}
+	if rows.Err() != nil {
+		return nil, errors.Wrapf(rows.Err(), "cannot query [%s] %ss", schema, kind)
+	}
+	return objects, nil
+}
+
+func compare(kind string, expected map[string]string, actual map[string]string) []Drift {
+	names := make([]string, 0, len(expected)+len(actual))
+	for name := range expected {
+		names = append(names, name)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var drifts []Drift
+	for _, name := range names {
+		e, eok := expected[name]
+		a, aok := actual[name]
+		if eok && aok && e == a {
+			continue
+		}
+		drifts = append(drifts, Drift{
+			Kind:     kind,
+			Name:     name,
+			Expected: present(e, eok),
+			Actual:   present(a, aok),
+		})
+	}
+	return drifts
+}
+
+// present returns definition of existing object, tables have no definition
+func present(def string, ok bool) string {
+	if !ok {
+		return ""
+	}
+	if def == "" {
+		return "present"
+	}
+	return def
+}
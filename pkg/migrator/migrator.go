@@ -5,4 +5,20 @@ import "context"
 type Migrator interface {
 	Up(context.Context) error
 	Down(context.Context) error
+	// Steps applies n migrations up, or reverts -n migrations down when n is negative
+	Steps(context.Context, int) error
+	Goto(context.Context, uint) error
+	// Version returns applied version and whether the last migration failed
+	Version() (uint, bool, error)
+	// Pending returns migrations which are not applied yet in order
+	Pending() ([]Migration, error)
+	// DryRun applies pending migrations in a transaction which is rolled back
+	DryRun(context.Context) ([]Migration, error)
+}
+
+// Migration is an up migration read from source.
+type Migration struct {
+	Version    uint   `json:"version" yaml:"version"`
+	Identifier string `json:"identifier" yaml:"identifier"`
+	SQL        string `json:"sql" yaml:"sql"`
 }
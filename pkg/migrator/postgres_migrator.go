@@ -5,12 +5,16 @@ import (
 	"embed"
 	"fmt"
 	"goapptemplate/pkg/postgres"
+	"io"
+	"os"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pkg/errors"
 )
@@ -18,6 +22,35 @@ import (
 const createSchema = "create schema if not exists"
 const dropSchema = "drop schema if exists"
 
+// Session level advisory lock serializing migration runs of a schema across instances
+const (
+	lockQuery   = "select pg_advisory_lock(hashtext($1))"
+	unlockQuery = "select pg_advisory_unlock(hashtext($1))"
+	lockPrefix  = "migrator:"
+)
+
+// DirtyError is returned when the last migration of schema failed, schema has to be
+// fixed manually and version forced before migrations can run again.
+type DirtyError struct {
+	Schema   string
+	Version  int
+	Previous int
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf(
+		"schema [%s] is dirty at version [%d], fix it manually and force version [%d] if migration is applied or [%d] if it is reverted",
+		e.Schema,
+		e.Version,
+		e.Version,
+		e.Previous,
+	)
+}
+
+func (e *DirtyError) Unwrap() error {
+	return migrate.ErrDirty{Version: e.Version}
+}
+
 func NewMigrationsSource(fs embed.FS, path string) (source.Driver, error) {
 	// Load migration source from embeded filesystem
 	src, err := iofs.New(fs, path)
@@ -27,15 +60,18 @@ func NewMigrationsSource(fs embed.FS, path string) (source.Driver, error) {
 	return src, nil
 }
 
+var _ Migrator = (*PostgresMigrator)(nil)
+
 type PostgresMigrator struct {
 	m      *migrate.Migrate
+	src    source.Driver
 	url    string
 	schema string
 }
 
 // Down implements migrator.Migrator.
 func (pm *PostgresMigrator) Down(ctx context.Context) error {
-	err := pm.m.Down()
+	err := pm.run(ctx, pm.m.Down)
 	if err != nil {
 		return errors.Wrapf(err, "cannot migrate [%s] down", pm.schema)
 	}
@@ -44,13 +80,297 @@ func (pm *PostgresMigrator) Down(ctx context.Context) error {
 
 // Up implements migrator.Migrator.
 func (pm *PostgresMigrator) Up(ctx context.Context) error {
-	err := pm.m.Up()
+	err := pm.run(ctx, pm.m.Up)
 	if err != nil {
 		return errors.Wrapf(err, "cannot migrate [%s] up", pm.schema)
 	}
 	return nil
 }
 
+// Steps applies n migrations up, or reverts -n migrations down when n is negative.
+func (pm *PostgresMigrator) Steps(ctx context.Context, n int) error {
+	err := pm.run(ctx, func() error {
+		return pm.m.Steps(n)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot migrate [%s] [%d] steps", pm.schema, n)
+	}
+	return nil
+}
+
+// Goto migrates up or down to version.
+func (pm *PostgresMigrator) Goto(ctx context.Context, version uint) error {
+	err := pm.run(ctx, func() error {
+		return pm.m.Migrate(version)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot migrate [%s] to version [%d]", pm.schema, version)
+	}
+	return nil
+}
+
+// Force sets version without running migrations and clears dirty flag,
+// -1 means no migrations are applied.
+func (pm *PostgresMigrator) Force(ctx context.Context, version int) error {
+	err := pm.run(ctx, func() error {
+		return pm.m.Force(version)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot force [%s] version [%d]", pm.schema, version)
+	}
+	return nil
+}
+
+// WaitVersion blocks until applied version reaches version or ctx is done, polling
+// every interval. Migrations are expected to be applied by another instance.
+func (pm *PostgresMigrator) WaitVersion(ctx context.Context, version uint, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		// Schema is dirty while migration is running, it is reported only when waiting is over
+		v, dirty, err := pm.m.Version()
+		switch {
+		case errors.Is(err, migrate.ErrNilVersion):
+		case err != nil:
+			return errors.Wrapf(err, "cannot read [%s] version", pm.schema)
+		case !dirty && v >= version:
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if dirty {
+				return pm.dirtyError(migrate.ErrDirty{Version: int(v)})
+			}
+			return errors.Wrapf(ctx.Err(), "schema [%s] has not reached version [%d]", pm.schema, version)
+		case <-t.C:
+		}
+	}
+}
+
+// Pending returns migrations which are not applied yet with their up SQL.
+func (pm *PostgresMigrator) Pending() ([]Migration, error) {
+	current, dirty, err := pm.m.Version()
+	applied := err == nil
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, errors.Wrapf(err, "cannot read [%s] version", pm.schema)
+	}
+	if dirty {
+		return nil, pm.dirtyError(migrate.ErrDirty{Version: int(current)})
+	}
+	versions, err := pm.Versions()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, v := range versions {
+		if applied && v <= current {
+			continue
+		}
+		m, ok, err := pm.readUp(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// DryRun executes pending migrations in a transaction which is rolled back, schema
+// version is not changed. Statements which cannot run in a transaction
+// (e.g. create index concurrently) fail.
+func (pm *PostgresMigrator) DryRun(ctx context.Context) ([]Migration, error) {
+	var pending []Migration
+	err := pm.withLock(ctx, func(conn *pgx.Conn) error {
+		var err error
+		pending, err = pm.Pending()
+		if err != nil {
+			return err
+		}
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return errors.Wrap(err, "cannot begin transaction")
+		}
+		defer tx.Rollback(context.Background())
+		_, err = tx.Exec(ctx, fmt.Sprintf("set local search_path to %s", pgx.Identifier{pm.schema}.Sanitize()))
+		if err != nil {
+			return errors.Wrapf(err, "cannot set search path to [%s]", pm.schema)
+		}
+		for _, m := range pending {
+			_, err = tx.Exec(ctx, m.SQL)
+			if err != nil {
+				return errors.Wrapf(err, "cannot execute migration [%d]", m.Version)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot dry run [%s] migrations", pm.schema)
+	}
+	return pending, nil
+}
+
+// stopOnDone wraps fn so that migrations are stopped gracefully once ctx is done,
+// migration being applied is finished and schema is not left dirty.
+func (pm *PostgresMigrator) stopOnDone(ctx context.Context, fn func() error) func() error {
+	return func() error {
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		// sent is written by goroutine before stopped is closed
+		var sent bool
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				select {
+				case pm.m.GracefulStop <- true:
+					sent = true
+				default:
+				}
+			case <-done:
+			}
+		}()
+		err := fn()
+		close(done)
+		<-stopped
+		if !sent {
+			return err
+		}
+		// Channel is buffered, stop left in it was sent after migrations finished,
+		// it must not stop the next run and the run is not reported stopped
+		select {
+		case <-pm.m.GracefulStop:
+			return err
+		default:
+		}
+		if err == nil || errors.Is(err, migrate.ErrNoChange) {
+			return errors.Wrap(ctx.Err(), "migrations are stopped")
+		}
+		return err
+	}
+}
+
+// run runs migrations with fn holding lock and stopping once ctx is done, checksums
+// of applied migrations are recorded afterwards.
+func (pm *PostgresMigrator) run(ctx context.Context, fn func() error) error {
+	return pm.withLock(ctx, func(conn *pgx.Conn) error {
+		err := pm.stopOnDone(ctx, fn)()
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+		recordErr := pm.recordChecksums(context.Background(), conn)
+		if recordErr != nil {
+			return recordErr
+		}
+		return err
+	})
+}
+
+// withLock runs fn holding advisory lock of schema migrations on lock connection,
+// waiting for lock is cancelled with ctx. Lock is released when connection is closed.
+func (pm *PostgresMigrator) withLock(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	conn, err := postgres.NewConn(ctx, pm.url, nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot create lock connection")
+	}
+	defer conn.Close(context.Background())
+	_, err = conn.Exec(ctx, lockQuery, lockPrefix+pm.schema)
+	if err != nil {
+		return errors.Wrapf(err, "cannot acquire [%s] migrations lock", pm.schema)
+	}
+	err = pm.dirtyError(fn(conn))
+	_, unlockErr := conn.Exec(context.Background(), unlockQuery, lockPrefix+pm.schema)
+	if err != nil {
+		return err
+	}
+	if unlockErr != nil {
+		return errors.Wrapf(unlockErr, "cannot release [%s] migrations lock", pm.schema)
+	}
+	return nil
+}
+
+// dirtyError converts migrate.ErrDirty to DirtyError with version to force when
+// failed migration is reverted.
+func (pm *PostgresMigrator) dirtyError(err error) error {
+	var dirty migrate.ErrDirty
+	if !errors.As(err, &dirty) {
+		return err
+	}
+	previous := -1
+	v, prevErr := pm.src.Prev(uint(dirty.Version))
+	if prevErr == nil {
+		previous = int(v)
+	}
+	return &DirtyError{
+		Schema:   pm.schema,
+		Version:  dirty.Version,
+		Previous: previous,
+	}
+}
+
+// Version returns applied version and whether the last migration failed,
+// migrate.ErrNilVersion is returned when no migrations are applied.
+func (pm *PostgresMigrator) Version() (uint, bool, error) {
+	return pm.m.Version()
+}
+
+// Versions returns available migration versions in ascending order.
+func (pm *PostgresMigrator) Versions() ([]uint, error) {
+	v, err := pm.src.First()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "cannot read first migration")
+	}
+	versions := []uint{v}
+	for {
+		v, err = pm.src.Next(v)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return versions, nil
+			}
+			return nil, errors.Wrapf(err, "cannot read migration after [%d]", v)
+		}
+		versions = append(versions, v)
+	}
+}
+
+// readUp reads up migration of version, false is returned when version has
+// down migration only.
+func (pm *PostgresMigrator) readUp(version uint) (Migration, bool, error) {
+	r, identifier, err := pm.src.ReadUp(version)
+	if errors.Is(err, os.ErrNotExist) {
+		return Migration{}, false, nil
+	}
+	if err != nil {
+		return Migration{}, false, errors.Wrapf(err, "cannot read migration [%d]", version)
+	}
+	defer r.Close()
+	sql, err := io.ReadAll(r)
+	if err != nil {
+		return Migration{}, false, errors.Wrapf(err, "cannot read migration [%d]", version)
+	}
+	return Migration{
+		Version:    version,
+		Identifier: identifier,
+		SQL:        string(sql),
+	}, true, nil
+}
+
+// Close closes source and database connections.
+func (pm *PostgresMigrator) Close() error {
+	srcErr, dbErr := pm.m.Close()
+	if srcErr != nil {
+		return errors.Wrap(srcErr, "cannot close source")
+	}
+	if dbErr != nil {
+		return errors.Wrap(dbErr, "cannot close database")
+	}
+	return nil
+}
+
 func (pm *PostgresMigrator) CreateSchema(ctx context.Context, schema string) error {
 	db, err := postgres.NewConn(ctx, pm.url, nil)
 	if err != nil {
@@ -58,19 +378,25 @@ func (pm *PostgresMigrator) CreateSchema(ctx context.Context, schema string) err
 	}
 	defer db.Close(ctx)
 	_, err = db.Exec(ctx, fmt.Sprintf("%s %s", createSchema, schema))
+	// Concurrent creation of the same schema fails even if not exists
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == postgres.ErrDuplicateSchema || pgErr.Code == postgres.ErrDuplicateKey) {
+		return nil
+	}
 	if err != nil {
 		return errors.Wrapf(err, "cannot execute create [%s] schema query", schema)
 	}
 	return nil
 }
 
+// DropSchema drops schema with all its objects.
 func (pm *PostgresMigrator) DropSchema(ctx context.Context, schema string) error {
 	db, err := postgres.NewConn(ctx, pm.url, nil)
 	if err != nil {
 		return errors.Wrap(err, "cannot create connection")
 	}
 	defer db.Close(ctx)
-	_, err = db.Exec(ctx, fmt.Sprintf("%s %s", dropSchema, schema))
+	_, err = db.Exec(ctx, fmt.Sprintf("%s %s cascade", dropSchema, pgx.Identifier{schema}.Sanitize()))
 	if err != nil {
 		return errors.Wrapf(err, "cannot execute drop [%s] schema query", schema)
 	}
@@ -97,5 +423,6 @@ func NewPostgresMigrator(url string, schema string, migrations embed.FS, path st
 		return nil, errors.Wrap(err, "cannot create migrate instance with source")
 	}
 	pm.m = m
+	pm.src = src
 	return pm, nil
 }
@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/pkg/errors"
+)
+
+func TestStopOnDone(t *testing.T) {
+	tests := []struct {
+		name string
+		// fn runs migrations, cancel is called when ctx is to be done meanwhile
+		fn   func(cancel func(), stop <-chan bool) error
+		want error
+	}{
+		{
+			name: "finished",
+			fn: func(cancel func(), stop <-chan bool) error {
+				return nil
+			},
+		},
+		{
+			name: "no change",
+			fn: func(cancel func(), stop <-chan bool) error {
+				return migrate.ErrNoChange
+			},
+			want: migrate.ErrNoChange,
+		},
+		{
+			name: "stopped",
+			fn: func(cancel func(), stop <-chan bool) error {
+				cancel()
+				// Migrate takes stop before the next migration
+				<-stop
+				return nil
+			},
+			want: context.Canceled,
+		},
+		{
+			name: "done after finished",
+			fn: func(cancel func(), stop <-chan bool) error {
+				cancel()
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := &PostgresMigrator{m: &migrate.Migrate{GracefulStop: make(chan bool, 1)}}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err := pm.stopOnDone(ctx, func() error {
+				return tt.fn(cancel, pm.m.GracefulStop)
+			})()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("stopOnDone() error = %v, want %v", err, tt.want)
+			}
+			if len(pm.m.GracefulStop) != 0 {
+				t.Fatal("stopOnDone() left stop for the next run")
+			}
+		})
+	}
+}
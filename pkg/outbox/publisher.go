@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+// Event is a domain event written to outbox table in transaction of the change.
+type Event struct {
+	// ID is outbox sequence number, events of an aggregate are published in ID order
+	ID            int64
+	AggregateType string
+	AggregateID   string
+	Type          string
+	// TenantID is empty for events of requests without tenant
+	TenantID string
+	// Payload is JSON encoded
+	Payload   []byte
+	CreatedAt time.Time
+	// Attempts is the number of failed publish attempts before this one
+	Attempts int
+}
+
+// Publisher delivers events downstream. Publish returns nil only when event is
+// accepted, otherwise it is retried, so events may be delivered more than once.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// PublisherFunc adapts function to Publisher.
+type PublisherFunc func(ctx context.Context, event *Event) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, event *Event) error {
+	return f(ctx, event)
+}
@@ -0,0 +1,286 @@
+package outbox
+
+import (
+	"context"
+	"goapptemplate/pkg/postgres"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// purgeInterval is how often published events older than retention are deleted
+const purgeInterval = time.Minute
+
+// pendingQuery locks the oldest pending event of each aggregate which is due.
+// Later events of an aggregate wait until earlier ones are published, also when
+// earlier ones are locked by another relay, so events of an aggregate are
+// published in order.
+const pendingQuery = `select id, aggregate_type, aggregate_id::text, event_type, tenant_id, payload, created_at, attempts
+from outbox o
+where published_at is null
+	and next_attempt_at <= now()
+	and not exists (
+		select 1
+		from outbox p
+		where p.aggregate_type = o.aggregate_type
+			and p.aggregate_id = o.aggregate_id
+			and p.published_at is null
+			and p.id < o.id
+	)
+order by id
+limit $1
+for update skip locked`
+
+const (
+	publishedQuery = "update outbox set published_at = now(), last_error = null where id = any($1)"
+	failedQuery    = "update outbox set attempts = attempts + 1, last_error = $2, next_attempt_at = now() + $3::float8 * interval '1 second' where id = $1"
+	purgeQuery     = "delete from outbox where published_at < now() - $1::float8 * interval '1 second'"
+)
+
+type RelayConfig struct {
+	// Interval of polling outbox for pending events, full batches are followed
+	// by the next one right away.
+	Interval time.Duration
+	// Batch is the maximum number of events published in one transaction.
+	Batch int
+	// Timeout of publishing a single event.
+	Timeout time.Duration
+	// BatchTimeout bounds publishing of a batch and so how long its events stay
+	// locked, events not published by then wait for the next poll.
+	BatchTimeout time.Duration
+	// Backoff is delay before retry of event failed to publish, doubled with
+	// every failed attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retention is the duration published events are kept for, 0 keeps them.
+	Retention time.Duration
+	// Schemas returns schemas outbox tables of which are relayed, e.g. schemas
+	// of tenants. Search path of connection is used when nil.
+	Schemas func(ctx context.Context) ([]string, error)
+}
+
+// Relay publishes events of outbox table. Events are published at least once,
+// event is marked published in the transaction holding its lock, so events of
+// a failed transaction are published again. Any number of relays may run.
+//
+// Relay transactions are not retried on serialization failure or deadlock, as
+// retry would publish the batch again, events are picked by the next poll instead.
+type Relay struct {
+	db        *postgres.PostgresDB
+	publisher Publisher
+	config    *RelayConfig
+	done      chan struct{}
+	mu        sync.Mutex
+	closed    bool
+	running   sync.WaitGroup
+	log       *logrus.Entry
+}
+
+// Run polls outbox until relay is closed.
+func (r *Relay) Run() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running.Add(1)
+	r.mu.Unlock()
+	defer r.running.Done()
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	var purged time.Time
+	for {
+		select {
+		case <-r.done:
+			return nil
+		case <-ticker.C:
+			ctx := context.Background()
+			schemas := []string{""}
+			if r.config.Schemas != nil {
+				var err error
+				schemas, err = r.config.Schemas(ctx)
+				if err != nil {
+					r.log.WithError(err).Error("cannot list outbox schemas")
+					continue
+				}
+			}
+			purge := r.config.Retention > 0 && time.Since(purged) >= purgeInterval
+			for _, schema := range schemas {
+				r.relay(ctx, schema)
+				if purge {
+					r.purge(ctx, schema)
+				}
+			}
+			if purge {
+				purged = time.Now()
+			}
+		}
+	}
+}
+
+// Close stops polling and waits for Run to finish batch being published, so
+// that publisher may be closed after.
+func (r *Relay) Close() error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.done)
+	}
+	r.mu.Unlock()
+	r.running.Wait()
+	return nil
+}
+
+// relay publishes due events of schema batch by batch.
+func (r *Relay) relay(ctx context.Context, schema string) {
+	if schema != "" {
+		ctx = postgres.WithSearchPath(ctx, schema)
+	}
+	for {
+		n, err := r.poll(ctx)
+		if err != nil {
+			r.log.WithError(err).WithField("schema", schema).Error("cannot relay outbox events")
+			return
+		}
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+		if n < r.config.Batch {
+			return
+		}
+	}
+}
+
+// poll publishes a batch of due events and returns its size, 0 when publishing
+// failed.
+func (r *Relay) poll(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.WithTx(ctx, pgx.TxOptions{}, func(ctx context.Context, tx pgx.Tx) error {
+		events, err := pending(ctx, tx, r.config.Batch)
+		if err != nil {
+			return err
+		}
+		n = len(events)
+		// Statements of transaction run past the deadline, so that published
+		// events are still marked
+		bctx, cancel := context.WithTimeout(ctx, r.config.BatchTimeout)
+		defer cancel()
+		published := make([]int64, 0, len(events))
+		for _, e := range events {
+			err := r.publish(bctx, e)
+			if err == nil {
+				published = append(published, e.ID)
+				continue
+			}
+			if bctx.Err() != nil {
+				// Batch ran out of time, the rest of it is not failed
+				r.log.WithField("events", len(events)-len(published)).Warn("Outbox batch timed out")
+				n = 0
+				break
+			}
+			delay := r.backoff(e.Attempts)
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"event_id":   e.ID,
+				"event_type": e.Type,
+				"attempts":   e.Attempts + 1,
+				"retry_in":   delay.String(),
+			}).Warn("cannot publish event")
+			_, err = tx.Exec(ctx, failedQuery, e.ID, err.Error(), delay.Seconds())
+			if err != nil {
+				return errors.Wrapf(err, "cannot record failed attempt of event [%d]", e.ID)
+			}
+			// Publisher is likely down, the rest of batch waits for the next poll
+			// instead of timing out one by one
+			n = 0
+			break
+		}
+		if len(published) == 0 {
+			return nil
+		}
+		_, err = tx.Exec(ctx, publishedQuery, published)
+		if err != nil {
+			return errors.Wrap(err, "cannot mark events published")
+		}
+		r.log.WithField("events", len(published)).Debug("Published events")
+		return nil
+	})
+	return n, err
+}
+
+func (r *Relay) publish(ctx context.Context, e *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, e)
+}
+
+// backoff returns delay before the next attempt of event failed attempts times before.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.config.Backoff
+	for i := 0; i < attempts && d < r.config.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.config.MaxBackoff {
+		d = r.config.MaxBackoff
+	}
+	return d
+}
+
+func (r *Relay) purge(ctx context.Context, schema string) {
+	if schema != "" {
+		ctx = postgres.WithSearchPath(ctx, schema)
+	}
+	err := r.db.WithTx(ctx, pgx.TxOptions{}, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, purgeQuery, r.config.Retention.Seconds())
+		return err
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("schema", schema).Error("cannot purge published outbox events")
+	}
+}
+
+func pending(ctx context.Context, tx pgx.Tx, limit int) ([]*Event, error) {
+	rows, err := tx.Query(ctx, pendingQuery, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot select pending events")
+	}
+	defer rows.Close()
+	var events []*Event
+	for rows.Next() {
+		var e Event
+		err := rows.Scan(
+			&e.ID,
+			&e.AggregateType,
+			&e.AggregateID,
+			&e.Type,
+			&e.TenantID,
+			&e.Payload,
+			&e.CreatedAt,
+			&e.Attempts,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot scan pending event")
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "cannot select pending events")
+	}
+	return events, nil
+}
+
+func NewRelay(db *postgres.PostgresDB, publisher Publisher, config *RelayConfig, logger *logrus.Logger) *Relay {
+	relayDB := *db
+	relayDB.Retry = postgres.Retry{}
+	return &Relay{
+		db:        &relayDB,
+		publisher: publisher,
+		config:    config,
+		done:      make(chan struct{}),
+		log:       logger.WithField("layer", "infrastructure.outbox.Relay"),
+	}
+}
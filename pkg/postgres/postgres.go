@@ -12,21 +12,86 @@ import (
 type DB interface {
 	BeginTx(ctx context.Context) (*pgxpool.Conn, pgx.Tx, error)
 	EndTx(context.Context, pgx.Tx) error
+	WithTx(ctx context.Context, opts pgx.TxOptions, fn TxFunc) error
+	Reader(ctx context.Context) Querier
 }
 
 type PostgresDB struct {
 	*pgxpool.Pool
+	// Retry of transactions run with WithTx failed with serialization failure or deadlock
+	Retry Retry
+	// Replicas run read only transactions of WithTx, primary is used when nil
+	Replicas *Replicas
 }
 
+// SettingTenantID is the setting row level security policies compare row tenant with
+const SettingTenantID = "app.tenant_id"
+
+// settingsQuery sets search path, unless $1 is empty, and $2 setting to $3 for the
+// current transaction. Statement is the same for any schema and tenant, so that
+// it is prepared once per connection.
+const settingsQuery = "select set_config('search_path', coalesce(nullif($1, ''), current_setting('search_path')), true), set_config($2, $3, true)"
+
+type searchPathKey struct{}
+
+type tenantIDKey struct{}
+
+// WithSearchPath returns a copy of ctx with schema transactions begun with it use,
+// e.g. schema of request tenant.
+func WithSearchPath(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, searchPathKey{}, schema)
+}
+
+// SearchPath returns schema set with WithSearchPath, empty when connection default is used.
+func SearchPath(ctx context.Context) string {
+	schema, _ := ctx.Value(searchPathKey{}).(string)
+	return schema
+}
+
+// searchPath returns search path of schema, empty for empty schema.
+func searchPath(schema string) string {
+	if schema == "" {
+		return ""
+	}
+	return pgx.Identifier{schema}.Sanitize()
+}
+
+// WithTenantID returns a copy of ctx with tenant ID transactions begun with it set
+// as SettingTenantID, so that row level security policies apply.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey{}, tenantID)
+}
+
+// TenantID returns tenant ID set with WithTenantID.
+func TenantID(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantIDKey{}).(string)
+	return tenantID
+}
+
+// BeginTx begins transaction on acquired connection, search path and tenant ID
+// are set for the transaction only when ctx carries them.
 func (db *PostgresDB) BeginTx(ctx context.Context) (*pgxpool.Conn, pgx.Tx, error) {
-	conn, err := db.Acquire(ctx)
+	return db.beginTx(ctx, db.Pool, pgx.TxOptions{})
+}
+
+func (db *PostgresDB) beginTx(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions) (*pgxpool.Conn, pgx.Tx, error) {
+	conn, err := pool.Acquire(ctx)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "cannot acquire connection from dbpool")
 	}
-	tx, err := conn.Begin(ctx)
+	tx, err := conn.BeginTx(ctx, opts)
 	if err != nil {
+		conn.Release()
 		return nil, nil, errors.Wrap(err, "cannot begin transaction")
 	}
+	if schema, tenantID := SearchPath(ctx), TenantID(ctx); schema != "" || tenantID != "" {
+		_, err = tx.Exec(ctx, settingsQuery, searchPath(schema), SettingTenantID, tenantID)
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			conn.Release()
+			return nil, nil, errors.Wrapf(err, "cannot set search path to [%s] and tenant ID to [%s]", schema, tenantID)
+		}
+	}
 	return conn, tx, nil
 }
 
@@ -44,7 +109,8 @@ func NewPostgresDB(ctx context.Context, config string, tracer pgx.QueryTracer) (
 		return nil, err
 	}
 	return &PostgresDB{
-		Pool: pool,
+		Pool:  pool,
+		Retry: DefaultRetry,
 	}, nil
 }
 
@@ -2,45 +2,218 @@ package postgres
 
 import (
 	"context"
+	"goapptemplate/pkg/logger"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
 
+const redactedArgs = "[REDACTED]"
+
+var (
+	sqlcNameRe = regexp.MustCompile(`^--\s*name:\s*(\S+)`)
+	commentRe  = regexp.MustCompile(`--[^\n]*`)
+	spaceRe    = regexp.MustCompile(`\s+`)
+)
+
+// QueryTracer traces queries, batches, copies and connects, and aggregates
+// per-statement stats.
+type QueryTracer interface {
+	pgx.QueryTracer
+	pgx.BatchTracer
+	pgx.CopyFromTracer
+	pgx.ConnectTracer
+	Stats() []*QueryStats
+	ResetStats()
+}
+
+type TracerConfig struct {
+	// SlowQueryThreshold makes queries that take longer to be logged at warn level,
+	// disabled when 0
+	SlowQueryThreshold time.Duration
+	// RedactArgs hides query arguments from logs
+	RedactArgs bool
+}
+
+type traceKey struct{}
+
+type trace struct {
+	start time.Time
+	sql   string
+	args  []any
+}
+
 type queryTracer struct {
-	log *logrus.Entry
+	log    *logrus.Entry
+	config *TracerConfig
+	stats  *statsCollector
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{
+		start: time.Now(),
+		sql:   data.SQL,
+		args:  data.Args,
+	})
 }
 
 // TraceQueryEnd implements pgx.QueryTracer.
 func (t *queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
-	t.log.WithFields(
-		map[string]interface{}{
-			"host":     conn.Config().Host,
-			"port":     conn.Config().Port,
-			"user":     conn.Config().User,
-			"database": conn.Config().Database,
-			"err":      data.Err,
-		},
-	).Debug("query execution end")
+	tr, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	d := time.Since(tr.start)
+	name, sql := QueryName(tr.sql), NormalizeSQL(tr.sql)
+	t.stats.observe(name, sql, d, data.Err)
+	t.logEnd(ctx, conn, d, data.Err, logrus.Fields{
+		"query": name,
+		"sql":   sql,
+		"args":  t.args(tr.args),
+		"rows":  data.CommandTag.RowsAffected(),
+	}, "query")
 }
 
-// TraceQueryStart implements pgx.QueryTracer.
-func (t *queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
-	t.log.WithFields(
-		map[string]interface{}{
-			"host":     conn.Config().Host,
-			"port":     conn.Config().Port,
-			"user":     conn.Config().User,
-			"database": conn.Config().Database,
-			"sql":      data.SQL,
-			"args":     data.Args,
-		},
-	).Debug("query execution start")
-	return ctx
-}
-
-func NewLogrusQueryTracer(logger *logrus.Logger) pgx.QueryTracer {
+// TraceBatchStart implements pgx.BatchTracer.
+func (t *queryTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{
+		start: time.Now(),
+	})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.
+func (t *queryTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	name, sql := QueryName(data.SQL), NormalizeSQL(data.SQL)
+	// Batched queries are sent at once, their individual durations are unknown
+	t.stats.observe(name, sql, -1, data.Err)
+	entry := t.connLog(ctx, conn).WithFields(logrus.Fields{
+		"query": name,
+		"sql":   sql,
+		"args":  t.args(data.Args),
+	})
+	if data.Err != nil {
+		entry.WithError(data.Err).Error("batch query execution failed")
+		return
+	}
+	entry.Debug("batch query executed")
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	tr, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	t.logEnd(ctx, conn, time.Since(tr.start), data.Err, logrus.Fields{}, "batch")
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer.
+func (t *queryTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{
+		start: time.Now(),
+		sql:   "COPY " + data.TableName.Sanitize() + " (" + strings.Join(data.ColumnNames, ", ") + ") FROM STDIN",
+	})
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer.
+func (t *queryTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	tr, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	d := time.Since(tr.start)
+	t.stats.observe("", tr.sql, d, data.Err)
+	t.logEnd(ctx, conn, d, data.Err, logrus.Fields{
+		"sql":  tr.sql,
+		"rows": data.CommandTag.RowsAffected(),
+	}, "copy")
+}
+
+// TraceConnectStart implements pgx.ConnectTracer.
+func (t *queryTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{
+		start: time.Now(),
+	})
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer.
+func (t *queryTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	tr, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	entry := logger.Ctx(ctx, t.log).WithField("duration", time.Since(tr.start).String())
+	if data.Err != nil {
+		entry.WithError(data.Err).Error("connection failed")
+		return
+	}
+	entry.WithFields(connFields(data.Conn)).Debug("connection established")
+}
+
+// Stats implements QueryTracer.
+func (t *queryTracer) Stats() []*QueryStats {
+	return t.stats.snapshot()
+}
+
+// ResetStats implements QueryTracer.
+func (t *queryTracer) ResetStats() {
+	t.stats.reset()
+}
+
+func (t *queryTracer) logEnd(ctx context.Context, conn *pgx.Conn, d time.Duration, err error, fields logrus.Fields, kind string) {
+	entry := t.connLog(ctx, conn).WithFields(fields).WithField("duration", d.String())
+	switch {
+	case err != nil:
+		entry.WithError(err).Errorf("%s execution failed", kind)
+	case t.config.SlowQueryThreshold > 0 && d >= t.config.SlowQueryThreshold:
+		entry.Warnf("slow %s", kind)
+	default:
+		entry.Debugf("%s executed", kind)
+	}
+}
+
+func (t *queryTracer) connLog(ctx context.Context, conn *pgx.Conn) *logrus.Entry {
+	return logger.Ctx(ctx, t.log).WithFields(connFields(conn))
+}
+
+func (t *queryTracer) args(args []any) any {
+	if t.config.RedactArgs {
+		return redactedArgs
+	}
+	return args
+}
+
+func connFields(conn *pgx.Conn) logrus.Fields {
+	return logrus.Fields{
+		"host":     conn.Config().Host,
+		"port":     conn.Config().Port,
+		"user":     conn.Config().User,
+		"database": conn.Config().Database,
+	}
+}
+
+// QueryName returns sqlc query name from "-- name: <Name> :<cmd>" comment, if any.
+func QueryName(sql string) string {
+	m := sqlcNameRe.FindStringSubmatch(strings.TrimSpace(sql))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// NormalizeSQL strips comments and collapses whitespace.
+func NormalizeSQL(sql string) string {
+	return strings.TrimSpace(spaceRe.ReplaceAllString(commentRe.ReplaceAllString(sql, " "), " "))
+}
+
+func NewLogrusQueryTracer(logger *logrus.Logger, config *TracerConfig) QueryTracer {
 	return &queryTracer{
-		log: logger.WithField("layer", "infrastructure.postgres.queryTracer"),
+		log:    logger.WithField("layer", "infrastructure.postgres.queryTracer"),
+		config: config,
+		stats:  newStatsCollector(),
 	}
 }
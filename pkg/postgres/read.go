@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// Querier runs statements, it satisfies sqlc generated DBTX.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Reader returns querier of single statement reads, which run on a pool connection
+// without explicit transaction. Statements are routed as read only transactions of
+// WithTx, falling back to primary when replica cannot be reached.
+//
+// Search path and tenant ID of ctx are set in the same round trip as the statement,
+// both run in implicit transaction of a pipelined batch. When ctx carries
+// transaction of db, statements run in it instead.
+func (db *PostgresDB) Reader(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{db}).(pgx.Tx); ok {
+		return tx
+	}
+	return &reader{db: db}
+}
+
+type reader struct {
+	db *PostgresDB
+}
+
+// Exec implements Querier.
+func (r *reader) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	rows, err := r.Query(ctx, sql, args...)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	rows.Close()
+	return rows.CommandTag(), rows.Err()
+}
+
+// Query implements Querier.
+func (r *reader) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	pool, rp := r.db.route(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	rows, err := query(ctx, pool, sql, args...)
+	var pgErr *pgconn.PgError
+	if err != nil && rp != nil && ctx.Err() == nil && !errors.As(err, &pgErr) {
+		r.db.Replicas.fail(rp, err)
+		rows, err = query(ctx, r.db.Pool, sql, args...)
+	}
+	return rows, err
+}
+
+// QueryRow implements Querier.
+func (r *reader) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, err := r.Query(ctx, sql, args...)
+	return &row{rows: rows, err: err}
+}
+
+// query runs statement on pool, preceded by settings of ctx in the same batch.
+func query(ctx context.Context, pool *pgxpool.Pool, sql string, args ...interface{}) (pgx.Rows, error) {
+	schema, tenantID := SearchPath(ctx), TenantID(ctx)
+	if schema == "" && tenantID == "" {
+		return pool.Query(ctx, sql, args...)
+	}
+	b := &pgx.Batch{}
+	b.Queue(settingsQuery, searchPath(schema), SettingTenantID, tenantID)
+	b.Queue(sql, args...)
+	br := pool.SendBatch(ctx, b)
+	_, err := br.Exec()
+	if err != nil {
+		_ = br.Close()
+		return nil, errors.Wrapf(err, "cannot set search path to [%s] and tenant ID to [%s]", schema, tenantID)
+	}
+	rows, err := br.Query()
+	if err != nil {
+		rows.Close()
+		_ = br.Close()
+		return nil, err
+	}
+	return &batchRows{Rows: rows, br: br}, nil
+}
+
+// batchRows closes batch, releasing its connection, when rows are closed.
+type batchRows struct {
+	pgx.Rows
+	br pgx.BatchResults
+}
+
+func (r *batchRows) Close() {
+	r.Rows.Close()
+	_ = r.br.Close()
+}
+
+func (r *batchRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+// row scans the first row of rows the way pgx.Conn.QueryRow does.
+type row struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	err := r.rows.Scan(dest...)
+	if err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
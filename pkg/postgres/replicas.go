@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// lagQuery returns replication lag in seconds. Replica which replayed everything
+// it received from a streaming WAL receiver is not lagging even if primary had no
+// writes for a while. Otherwise, e.g. with replication disconnected, lag is the age
+// of the last replayed transaction, NULL when none was replayed. Roles without
+// pg_read_all_stats do not see receiver status, running receiver is trusted then.
+const lagQuery = `select case
+	when not pg_is_in_recovery() then 0
+	when pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+		and exists (select 1 from pg_stat_wal_receiver where coalesce(status, 'streaming') = 'streaming') then 0
+	else extract(epoch from now() - pg_last_xact_replay_timestamp())
+end::float8`
+
+type primaryKey struct{}
+
+// WithPrimary returns a copy of ctx read only transactions of which run on primary,
+// e.g. right after the client wrote, so that it reads its own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Primary reports whether ctx sticks to primary.
+func Primary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type ReplicasConfig struct {
+	// MaxLag is replication lag tolerated for reads, lagging replicas are not used.
+	MaxLag time.Duration
+	// Interval of replicas health checks, 0 disables checks after the first one.
+	Interval time.Duration
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	name    string
+	healthy atomic.Bool
+}
+
+// Replicas are read replica pools used round robin, replicas failing health checks
+// or lagging behind primary more than MaxLag are skipped until they recover.
+type Replicas struct {
+	config   *ReplicasConfig
+	replicas []*replica
+	next     atomic.Uint64
+	done     chan struct{}
+	once     sync.Once
+	log      *logrus.Entry
+}
+
+// Close stops health checks and closes replica pools.
+func (r *Replicas) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+		for _, rp := range r.replicas {
+			rp.pool.Close()
+		}
+	})
+	return nil
+}
+
+// pick returns the next healthy replica, nil when there is none.
+func (r *Replicas) pick() *replica {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		rp := r.replicas[(start+i)%n]
+		if rp.healthy.Load() {
+			return rp
+		}
+	}
+	return nil
+}
+
+// fail takes replica out of rotation until the next successful health check.
+func (r *Replicas) fail(rp *replica, err error) {
+	if rp.healthy.Swap(false) {
+		r.log.WithError(err).WithField("replica", rp.name).Warn("Replica is unhealthy, reading from primary")
+	}
+}
+
+// check updates health of every replica.
+func (r *Replicas) check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, rp := range r.replicas {
+		wg.Add(1)
+		go func(rp *replica) {
+			defer wg.Done()
+			var lag *float64
+			err := rp.pool.QueryRow(ctx, lagQuery).Scan(&lag)
+			if err != nil {
+				r.fail(rp, errors.Wrap(err, "cannot query replication lag"))
+				return
+			}
+			if r.config.MaxLag > 0 && lag == nil {
+				r.fail(rp, errors.New("replica is not streaming and has not replayed any transaction"))
+				return
+			}
+			var d time.Duration
+			if lag != nil {
+				d = time.Duration(*lag * float64(time.Second))
+			}
+			if r.config.MaxLag > 0 && d > r.config.MaxLag {
+				r.fail(rp, fmt.Errorf("replication lag [%s] exceeds [%s]", d.Round(time.Millisecond), r.config.MaxLag))
+				return
+			}
+			if !rp.healthy.Swap(true) {
+				r.log.WithField("replica", rp.name).WithField("lag", d.String()).Info("Replica is healthy")
+			}
+		}(rp)
+	}
+	wg.Wait()
+}
+
+func (r *Replicas) watch() {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.config.Interval)
+			r.check(ctx)
+			cancel()
+		}
+	}
+}
+
+// NewReplicas creates pools of replicas and checks their health, replicas which
+// cannot be reached are not used until they recover.
+func NewReplicas(ctx context.Context, configs []string, tracer pgx.QueryTracer, config *ReplicasConfig, logger *logrus.Logger) (*Replicas, error) {
+	r := &Replicas{
+		config: config,
+		done:   make(chan struct{}),
+		log:    logger.WithField("layer", "infrastructure.postgres.Replicas"),
+	}
+	for _, c := range configs {
+		pool, err := NewPool(ctx, c, tracer)
+		if err != nil {
+			r.Close()
+			return nil, errors.Wrap(err, "cannot create replica pool")
+		}
+		cc := pool.Config().ConnConfig
+		rp := &replica{
+			pool: pool,
+			name: fmt.Sprintf("%s:%d", cc.Host, cc.Port),
+		}
+		// Replicas failing the first check are reported as turned unhealthy
+		rp.healthy.Store(true)
+		r.replicas = append(r.replicas, rp)
+	}
+	r.check(ctx)
+	if config.Interval > 0 {
+		go r.watch()
+	}
+	return r, nil
+}
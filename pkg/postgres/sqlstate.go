@@ -1,5 +1,8 @@
 package postgres
 
 const (
-	ErrDuplicateKey = "23505"
+	ErrDuplicateKey         = "23505"
+	ErrDuplicateSchema      = "42P06"
+	ErrSerializationFailure = "40001"
+	ErrDeadlockDetected     = "40P01"
 )
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsSamples caps durations kept per statement for percentiles estimation.
+const statsSamples = 1024
+
+// QueryStats aggregates executions of a single statement.
+type QueryStats struct {
+	Name   string        `json:"name,omitempty"`
+	SQL    string        `json:"sql"`
+	Count  int64         `json:"count"`
+	Errors int64         `json:"errors"`
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+	P50    time.Duration `json:"p50"`
+	P95    time.Duration `json:"p95"`
+}
+
+type statementStats struct {
+	QueryStats
+	samples []time.Duration
+	next    int
+}
+
+type statsCollector struct {
+	mu    sync.Mutex
+	stmts map[string]*statementStats
+}
+
+// observe records statement execution, negative duration means it is unknown.
+func (sc *statsCollector) observe(name string, sql string, d time.Duration, err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	s, ok := sc.stmts[sql]
+	if !ok {
+		s = &statementStats{
+			QueryStats: QueryStats{
+				Name: name,
+				SQL:  sql,
+			},
+		}
+		sc.stmts[sql] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	if d < 0 {
+		return
+	}
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+	// Ring buffer of the latest samples
+	if len(s.samples) < statsSamples {
+		s.samples = append(s.samples, d)
+	} else {
+		s.samples[s.next] = d
+		s.next = (s.next + 1) % statsSamples
+	}
+}
+
+// snapshot returns stats sorted by total duration descending.
+func (sc *statsCollector) snapshot() []*QueryStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	stats := make([]*QueryStats, 0, len(sc.stmts))
+	for _, s := range sc.stmts {
+		qs := s.QueryStats
+		if len(s.samples) > 0 {
+			sorted := make([]time.Duration, len(s.samples))
+			copy(sorted, s.samples)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			qs.P50 = percentile(sorted, 0.50)
+			qs.P95 = percentile(sorted, 0.95)
+		}
+		stats = append(stats, &qs)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Total > stats[j].Total })
+	return stats
+}
+
+func (sc *statsCollector) reset() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.stmts = make(map[string]*statementStats)
+}
+
+// percentile returns nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		stmts: make(map[string]*statementStats),
+	}
+}
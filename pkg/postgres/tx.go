@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// TxFunc is run by WithTx in transaction, ctx carries the transaction so that
+// nested WithTx calls reuse it.
+type TxFunc func(ctx context.Context, tx pgx.Tx) error
+
+// Retry of transactions failed with serialization failure or deadlock, delay
+// doubles with every retry up to MaxBackoff and is jittered.
+type Retry struct {
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultRetry = Retry{
+	Retries:    3,
+	Backoff:    50 * time.Millisecond,
+	MaxBackoff: time.Second,
+}
+
+// delay returns jittered delay before retry attempt, first retry attempt is 0.
+func (r Retry) delay(attempt int) time.Duration {
+	d := r.Backoff
+	for i := 0; i < attempt && (r.MaxBackoff <= 0 || d < r.MaxBackoff); i++ {
+		d *= 2
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// txKey carries transaction of db, transactions of different databases do not nest
+type txKey struct {
+	db *PostgresDB
+}
+
+// WithTx runs fn in transaction begun with opts, which is committed when fn
+// returns nil and rolled back otherwise. Error of fn is returned as is.
+//
+// Read only transactions run on db.Replicas when there are healthy ones, see route.
+//
+// Transaction failed with serialization failure or deadlock is retried as a whole
+// according to db.Retry, so fn must not have side effects outside of it.
+//
+// When ctx already carries transaction of db, fn runs in a savepoint of it instead,
+// opts are ignored then and retry is left to the outermost call. So usecases may
+// compose repository calls in one atomic transaction.
+func (db *PostgresDB) WithTx(ctx context.Context, opts pgx.TxOptions, fn TxFunc) error {
+	if tx, ok := ctx.Value(txKey{db}).(pgx.Tx); ok {
+		return db.savepoint(ctx, tx, fn)
+	}
+	for attempt := 0; ; attempt++ {
+		err := db.runTx(ctx, opts, fn)
+		if err == nil || !IsRetryable(err) || attempt >= db.Retry.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(db.Retry.delay(attempt)):
+		}
+	}
+}
+
+func (db *PostgresDB) runTx(ctx context.Context, opts pgx.TxOptions, fn TxFunc) error {
+	pool, rp := db.route(ctx, opts)
+	conn, tx, err := db.beginTx(ctx, pool, opts)
+	if err != nil && rp != nil && ctx.Err() == nil {
+		db.Replicas.fail(rp, err)
+		conn, tx, err = db.beginTx(ctx, db.Pool, opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	defer tx.Rollback(ctx)
+
+	err = fn(context.WithValue(ctx, txKey{db}, tx), tx)
+	if err != nil {
+		return err
+	}
+	return db.EndTx(ctx, tx)
+}
+
+// route returns pool transaction begun with opts runs on. Read only transactions
+// run on a healthy replica unless ctx sticks to primary, serializable ones always
+// run on primary as replicas do not support them.
+func (db *PostgresDB) route(ctx context.Context, opts pgx.TxOptions) (*pgxpool.Pool, *replica) {
+	if db.Replicas == nil || opts.AccessMode != pgx.ReadOnly || opts.IsoLevel == pgx.Serializable || Primary(ctx) {
+		return db.Pool, nil
+	}
+	rp := db.Replicas.pick()
+	if rp == nil {
+		return db.Pool, nil
+	}
+	return rp.pool, rp
+}
+
+func (db *PostgresDB) savepoint(ctx context.Context, tx pgx.Tx, fn TxFunc) error {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "cannot create savepoint")
+	}
+	defer sp.Rollback(ctx)
+
+	err = fn(context.WithValue(ctx, txKey{db}, sp), sp)
+	if err != nil {
+		return err
+	}
+	err = sp.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "cannot release savepoint")
+	}
+	return nil
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock, which
+// succeed when transaction is retried.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == ErrSerializationFailure || pgErr.Code == ErrDeadlockDetected
+}
@@ -0,0 +1,125 @@
+package publisher
+
+import (
+	"context"
+	"goapptemplate/pkg/outbox"
+	"sync"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// headerPrefixAMQP prefixes binary mode attributes of AMQP binding
+const headerPrefixAMQP = "cloudEvents:"
+
+type AMQPConfig struct {
+	URL string
+	// Exchange events are published to, default exchange routes by queue name.
+	Exchange string
+	// RoutingKey is prefix of routing keys, followed by event type, e.g.
+	// events.BookCreated.
+	RoutingKey string
+}
+
+type amqpPublisher struct {
+	encoder *Encoder
+	config  *AMQPConfig
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	log     *logrus.Entry
+}
+
+// Publish implements outbox.Publisher, event is published once confirmed by
+// broker. Messages are persistent, connection is re-established by the next
+// publish after it is lost.
+func (p *amqpPublisher) Publish(ctx context.Context, event *outbox.Event) error {
+	m, err := p.encoder.Encode(event, headerPrefixAMQP)
+	if err != nil {
+		return err
+	}
+	headers := make(amqp.Table, len(m.Headers))
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch, err := p.channel()
+	if err != nil {
+		return err
+	}
+	key := event.Type
+	if p.config.RoutingKey != "" {
+		key = p.config.RoutingKey + "." + event.Type
+	}
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, p.config.Exchange, key, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  m.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.ID,
+		Timestamp:    event.CreatedAt,
+		Body:         m.Body,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot publish event [%s]", m.ID)
+	}
+	ack, err := dc.WaitContext(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "cannot confirm event [%s]", m.ID)
+	}
+	if !ack {
+		return errors.Errorf("event [%s] is not acknowledged by broker", m.ID)
+	}
+	return nil
+}
+
+// channel returns channel in confirm mode, connecting when connection is closed.
+func (p *amqpPublisher) channel() (*amqp.Channel, error) {
+	if p.ch != nil && !p.ch.IsClosed() {
+		return p.ch, nil
+	}
+	if p.conn == nil || p.conn.IsClosed() {
+		conn, err := amqp.Dial(p.config.URL)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot connect to amqp")
+		}
+		p.conn = conn
+		p.log.Debug("AMQP connected")
+	}
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open amqp channel")
+	}
+	err = ch.Confirm(false)
+	if err != nil {
+		_ = ch.Close()
+		return nil, errors.Wrap(err, "cannot put amqp channel in confirm mode")
+	}
+	p.ch = ch
+	return ch, nil
+}
+
+// Close implements Publisher.
+func (p *amqpPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil || p.conn.IsClosed() {
+		return nil
+	}
+	return p.conn.Close()
+}
+
+// NewAMQP connects to AMQP 0-9-1 broker, e.g. RabbitMQ.
+func NewAMQP(encoder *Encoder, config *AMQPConfig, logger *logrus.Logger) (Publisher, error) {
+	p := &amqpPublisher{
+		encoder: encoder,
+		config:  config,
+		log:     logger.WithField("layer", "infrastructure.publisher.AMQP"),
+	}
+	_, err := p.channel()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,160 @@
+package publisher
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// testExchange is a topic exchange every AMQP broker declares
+const testExchange = "amq.topic"
+
+// newTestAMQP returns publisher to AMQP_TEST_URL broker with routing key prefix
+// unique to the test and channel of the same broker for assertions.
+func newTestAMQP(t *testing.T, mode string, exchange string) (Publisher, *amqp.Channel, string) {
+	t.Helper()
+	url := os.Getenv("AMQP_TEST_URL")
+	if url == "" {
+		t.Skip("AMQP_TEST_URL is not set")
+	}
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	ch, err := conn.Channel()
+	if err != nil {
+		t.Fatalf("Channel() error = %v", err)
+	}
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	prefix := "test" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	p, err := NewAMQP(newTestEncoder(t, mode), &AMQPConfig{URL: url, Exchange: exchange, RoutingKey: prefix}, l)
+	if err != nil {
+		t.Fatalf("NewAMQP() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p, ch, prefix
+}
+
+// bindTestQueue declares exclusive queue bound to testExchange by key.
+func bindTestQueue(t *testing.T, ch *amqp.Channel, key string, args amqp.Table) string {
+	t.Helper()
+	q, err := ch.QueueDeclare("", false, true, true, false, args)
+	if err != nil {
+		t.Fatalf("QueueDeclare() error = %v", err)
+	}
+	err = ch.QueueBind(q.Name, key, testExchange, false, nil)
+	if err != nil {
+		t.Fatalf("QueueBind() error = %v", err)
+	}
+	return q.Name
+}
+
+func TestAMQPPublish(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		contentType string
+		headers     map[string]string
+	}{
+		{
+			name:        "binary",
+			mode:        ModeBinary,
+			contentType: ContentTypeJSON,
+			headers: map[string]string{
+				"cloudEvents:specversion": "1.0",
+				"cloudEvents:id":          "acme-42",
+				"cloudEvents:type":        "test.BookCreated",
+				"cloudEvents:source":      "/test",
+				"cloudEvents:tenantid":    "acme",
+			},
+		},
+		{
+			name:        "structured",
+			mode:        ModeStructured,
+			contentType: ContentTypeStructured,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ch, prefix := newTestAMQP(t, tt.mode, testExchange)
+			q := bindTestQueue(t, ch, prefix+".BookCreated", nil)
+			e := testEvent("acme")
+			err := p.Publish(context.Background(), e)
+			if err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			// Confirmed event is routed already
+			d, ok, err := ch.Get(q, true)
+			if err != nil || !ok {
+				t.Fatalf("Get() = %v, %v, want published event", ok, err)
+			}
+			if d.RoutingKey != prefix+".BookCreated" {
+				t.Errorf("Publish() routing key = %q, want %q", d.RoutingKey, prefix+".BookCreated")
+			}
+			if d.ContentType != tt.contentType {
+				t.Errorf("Publish() content type = %q, want %q", d.ContentType, tt.contentType)
+			}
+			if d.MessageId != "acme-42" {
+				t.Errorf("Publish() message ID = %q, want %q", d.MessageId, "acme-42")
+			}
+			if d.DeliveryMode != amqp.Persistent {
+				t.Errorf("Publish() delivery mode = %d, want persistent", d.DeliveryMode)
+			}
+			for k := range d.Headers {
+				// Structured events carry attributes in the body only
+				if tt.mode == ModeStructured && strings.HasPrefix(k, headerPrefixAMQP) {
+					t.Errorf("Publish() header %s, want none of structured event", k)
+				}
+			}
+			for k, v := range tt.headers {
+				if got, _ := d.Headers[k].(string); got != v {
+					t.Errorf("Publish() header %s = %q, want %q", k, got, v)
+				}
+			}
+			if tt.mode == ModeBinary && string(d.Body) != string(e.Payload) {
+				t.Errorf("Publish() body = %s, want %s", d.Body, e.Payload)
+			}
+		})
+	}
+}
+
+func TestAMQPPublishWaitsForConfirm(t *testing.T) {
+	t.Run("nack", func(t *testing.T) {
+		p, ch, prefix := newTestAMQP(t, ModeBinary, testExchange)
+		// Broker nacks messages routed to full queue rejecting publishes
+		bindTestQueue(t, ch, prefix+".BookCreated", amqp.Table{
+			"x-max-length": int32(0),
+			"x-overflow":   "reject-publish",
+		})
+		err := p.Publish(context.Background(), testEvent("acme"))
+		if err == nil || !strings.Contains(err.Error(), "not acknowledged") {
+			t.Fatalf("Publish() error = %v, want not acknowledged", err)
+		}
+	})
+	t.Run("channel closed", func(t *testing.T) {
+		// Publishing to missing exchange closes channel before confirm
+		p, _, _ := newTestAMQP(t, ModeBinary, "goapptemplate.test.missing")
+		err := p.Publish(context.Background(), testEvent("acme"))
+		if err == nil {
+			t.Fatal("Publish() error = nil, want error of missing exchange")
+		}
+	})
+	t.Run("context done", func(t *testing.T) {
+		p, _, _ := newTestAMQP(t, ModeBinary, testExchange)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := p.Publish(ctx, testEvent("acme"))
+		if err == nil {
+			t.Fatal("Publish() error = nil, want context canceled")
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package publisher
+
+import (
+	"encoding/json"
+	"goapptemplate/pkg/outbox"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ModeStructured encodes the whole event as JSON envelope in message body
+	ModeStructured = "structured"
+	// ModeBinary carries event data in message body and attributes in transport headers
+	ModeBinary = "binary"
+)
+
+const (
+	SpecVersion           = "1.0"
+	ContentTypeStructured = "application/cloudevents+json"
+	ContentTypeJSON       = "application/json"
+)
+
+var ErrMode = errors.New("unknown cloudevents mode")
+
+// CloudEvent is CloudEvents 1.0 envelope of outbox event.
+type CloudEvent struct {
+	SpecVersion string `json:"specversion"`
+	// ID is outbox sequence number prefixed by tenant ID, sequences of schema per
+	// tenant outboxes overlap
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	// PartitionKey is partitioning extension, events of an aggregate share the key
+	PartitionKey string `json:"partitionkey,omitempty"`
+	// TenantID is extension set for events of tenant requests
+	TenantID string          `json:"tenantid,omitempty"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Attributes returns context attributes but data content type, which is mapped to
+// content type of transports, formatted as header values.
+func (ce *CloudEvent) Attributes() map[string]string {
+	attrs := map[string]string{
+		"specversion": ce.SpecVersion,
+		"id":          ce.ID,
+		"source":      ce.Source,
+		"type":        ce.Type,
+		"time":        ce.Time.Format(time.RFC3339Nano),
+	}
+	if ce.Subject != "" {
+		attrs["subject"] = ce.Subject
+	}
+	if ce.PartitionKey != "" {
+		attrs["partitionkey"] = ce.PartitionKey
+	}
+	if ce.TenantID != "" {
+		attrs["tenantid"] = ce.TenantID
+	}
+	return attrs
+}
+
+// Message is event encoded for a transport.
+type Message struct {
+	// ID is the event ID attribute
+	ID string
+	// Key is the aggregate ID, transports with partitions route by it
+	Key string
+	// Headers carry attributes in binary mode, names prefixed as transport binding
+	// requires
+	Headers     map[string]string
+	ContentType string
+	Body        []byte
+}
+
+// Encoder converts outbox events to CloudEvents messages.
+type Encoder struct {
+	mode       string
+	source     string
+	typePrefix string
+}
+
+// Event returns envelope of e, type is event type prefixed by type prefix.
+func (enc *Encoder) Event(e *outbox.Event) *CloudEvent {
+	id := strconv.FormatInt(e.ID, 10)
+	if e.TenantID != "" {
+		id = e.TenantID + "-" + id
+	}
+	return &CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              id,
+		Source:          enc.source,
+		Type:            enc.typePrefix + e.Type,
+		Subject:         e.AggregateID,
+		Time:            e.CreatedAt.UTC(),
+		DataContentType: ContentTypeJSON,
+		PartitionKey:    e.AggregateID,
+		TenantID:        e.TenantID,
+		Data:            e.Payload,
+	}
+}
+
+// Encode returns message of e, attribute headers of binary mode are named with
+// prefix of transport binding, e.g. "ce-" for HTTP.
+func (enc *Encoder) Encode(e *outbox.Event, prefix string) (*Message, error) {
+	ce := enc.Event(e)
+	m := &Message{ID: ce.ID, Key: e.AggregateID}
+	if enc.mode == ModeBinary {
+		attrs := ce.Attributes()
+		m.Headers = make(map[string]string, len(attrs))
+		for name, v := range attrs {
+			m.Headers[prefix+name] = v
+		}
+		m.ContentType = ce.DataContentType
+		m.Body = ce.Data
+		return m, nil
+	}
+	b, err := json.Marshal(ce)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot marshal event [%s]", ce.ID)
+	}
+	m.ContentType = ContentTypeStructured
+	m.Body = b
+	return m, nil
+}
+
+// NewEncoder creates encoder of mode, source is the source attribute of events.
+func NewEncoder(mode, source, typePrefix string) (*Encoder, error) {
+	switch mode {
+	case ModeStructured, ModeBinary:
+	default:
+		return nil, errors.Wrapf(ErrMode, "[%s]", mode)
+	}
+	if source == "" {
+		return nil, errors.New("cloudevents source is required")
+	}
+	return &Encoder{
+		mode:       mode,
+		source:     source,
+		typePrefix: typePrefix,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package publisher
+
+import (
+	"encoding/json"
+	"goapptemplate/pkg/outbox"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func testEvent(tenantID string) *outbox.Event {
+	return &outbox.Event{
+		ID:            42,
+		AggregateType: "book",
+		AggregateID:   "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+		Type:          "BookCreated",
+		TenantID:      tenantID,
+		Payload:       []byte(`{"name":"book"}`),
+		CreatedAt:     time.Date(2023, 10, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	}
+}
+
+func newTestEncoder(t *testing.T, mode string) *Encoder {
+	t.Helper()
+	enc, err := NewEncoder(mode, "/test", "test.")
+	if err != nil {
+		t.Fatalf("NewEncoder() error = %v", err)
+	}
+	return enc
+}
+
+func TestEncodeBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		tenantID string
+		want     map[string]string
+	}{
+		{
+			name:   "http",
+			prefix: headerPrefixHTTP,
+			want: map[string]string{
+				"ce-specversion":  "1.0",
+				"ce-id":           "42",
+				"ce-source":       "/test",
+				"ce-type":         "test.BookCreated",
+				"ce-time":         "2023-10-01T10:00:00Z",
+				"ce-subject":      "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+				"ce-partitionkey": "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+			},
+		},
+		{
+			name:     "kafka with tenant",
+			prefix:   headerPrefixKafka,
+			tenantID: "acme",
+			want: map[string]string{
+				"ce_specversion":  "1.0",
+				"ce_id":           "acme-42",
+				"ce_source":       "/test",
+				"ce_type":         "test.BookCreated",
+				"ce_time":         "2023-10-01T10:00:00Z",
+				"ce_subject":      "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+				"ce_partitionkey": "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+				"ce_tenantid":     "acme",
+			},
+		},
+		{
+			name:   "amqp",
+			prefix: headerPrefixAMQP,
+			want: map[string]string{
+				"cloudEvents:specversion":  "1.0",
+				"cloudEvents:id":           "42",
+				"cloudEvents:source":       "/test",
+				"cloudEvents:type":         "test.BookCreated",
+				"cloudEvents:time":         "2023-10-01T10:00:00Z",
+				"cloudEvents:subject":      "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+				"cloudEvents:partitionkey": "7d8f1b1e-0d1c-4b4e-9c3f-0f5d5d6c1a11",
+			},
+		},
+	}
+	enc := newTestEncoder(t, ModeBinary)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testEvent(tt.tenantID)
+			m, err := enc.Encode(e, tt.prefix)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if len(m.Headers) != len(tt.want) {
+				t.Errorf("Encode() headers = %v, want %v", m.Headers, tt.want)
+			}
+			for k, v := range tt.want {
+				if m.Headers[k] != v {
+					t.Errorf("Encode() header %s = %q, want %q", k, m.Headers[k], v)
+				}
+			}
+			if m.ID != tt.want[tt.prefix+"id"] || m.Key != e.AggregateID {
+				t.Errorf("Encode() id = %q, key = %q", m.ID, m.Key)
+			}
+			if m.ContentType != ContentTypeJSON || string(m.Body) != string(e.Payload) {
+				t.Errorf("Encode() content type = %q, body = %s, want payload as %s", m.ContentType, m.Body, ContentTypeJSON)
+			}
+		})
+	}
+}
+
+func TestEncodeStructured(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenantID string
+		wantID   string
+	}{
+		{name: "without tenant", wantID: "42"},
+		{name: "with tenant", tenantID: "acme", wantID: "acme-42"},
+	}
+	enc := newTestEncoder(t, ModeStructured)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := testEvent(tt.tenantID)
+			m, err := enc.Encode(e, headerPrefixHTTP)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if len(m.Headers) != 0 {
+				t.Errorf("Encode() headers = %v, want none", m.Headers)
+			}
+			if m.ContentType != ContentTypeStructured {
+				t.Errorf("Encode() content type = %q, want %q", m.ContentType, ContentTypeStructured)
+			}
+			var envelope map[string]any
+			if err := json.Unmarshal(m.Body, &envelope); err != nil {
+				t.Fatalf("Encode() body is not JSON: %v", err)
+			}
+			want := map[string]any{
+				"specversion":     "1.0",
+				"id":              tt.wantID,
+				"source":          "/test",
+				"type":            "test.BookCreated",
+				"subject":         e.AggregateID,
+				"time":            "2023-10-01T10:00:00Z",
+				"datacontenttype": ContentTypeJSON,
+				"partitionkey":    e.AggregateID,
+				"data":            map[string]any{"name": "book"},
+			}
+			if tt.tenantID != "" {
+				want["tenantid"] = tt.tenantID
+			}
+			if len(envelope) != len(want) {
+				t.Errorf("Encode() envelope = %v, want %v", envelope, want)
+			}
+			for k, v := range want {
+				got, _ := json.Marshal(envelope[k])
+				exp, _ := json.Marshal(v)
+				if string(got) != string(exp) {
+					t.Errorf("Encode() envelope %s = %s, want %s", k, got, exp)
+				}
+			}
+			if m.ID != tt.wantID {
+				t.Errorf("Encode() id = %q, want %q", m.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		source  string
+		wantErr bool
+	}{
+		{name: "structured", mode: ModeStructured, source: "/test"},
+		{name: "binary", mode: ModeBinary, source: "/test"},
+		{name: "unknown mode", mode: "batched", source: "/test", wantErr: true},
+		{name: "without source", mode: ModeBinary, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEncoder(tt.mode, tt.source, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEncoder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.mode == "batched" && !errors.Is(err, ErrMode) {
+				t.Fatalf("NewEncoder() error = %v, want %v", err, ErrMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package publisher
+
+import (
+	"context"
+	"goapptemplate/pkg/outbox"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// headerPrefixKafka prefixes binary mode attributes of Kafka binding
+const headerPrefixKafka = "ce_"
+
+type KafkaConfig struct {
+	Brokers []string
+	Topic   string
+}
+
+type kafkaPublisher struct {
+	encoder *Encoder
+	writer  *kafka.Writer
+}
+
+// Publish implements outbox.Publisher, event is published once acknowledged by
+// all in-sync replicas. Messages are keyed by aggregate ID, so events of an
+// aggregate land in one partition in order.
+func (p *kafkaPublisher) Publish(ctx context.Context, event *outbox.Event) error {
+	m, err := p.encoder.Encode(event, headerPrefixKafka)
+	if err != nil {
+		return err
+	}
+	headers := make([]kafka.Header, 0, len(m.Headers)+1)
+	for k, v := range m.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	headers = append(headers, kafka.Header{Key: "content-type", Value: []byte(m.ContentType)})
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(m.Key),
+		Value:   m.Body,
+		Headers: headers,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot write event [%s] to topic [%s]", m.ID, p.writer.Topic)
+	}
+	return nil
+}
+
+// Close implements Publisher.
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// NewKafka creates publisher writing events to topic, brokers are connected on
+// the first write.
+func NewKafka(encoder *Encoder, config *KafkaConfig) (Publisher, error) {
+	if len(config.Brokers) == 0 || config.Topic == "" {
+		return nil, errors.New("kafka brokers and topic are required")
+	}
+	return &kafkaPublisher{
+		encoder: encoder,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(config.Brokers...),
+			Topic:        config.Topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// Relay publishes one event at a time, waiting for a batch to fill only
+			// delays it
+			BatchSize: 1,
+		},
+	}, nil
+}
@@ -0,0 +1,177 @@
+package publisher
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// fakeKafkaTransport is a single broker cluster with a two partition topic,
+// produced records are kept in memory.
+type fakeKafkaTransport struct {
+	topic   string
+	mu      sync.Mutex
+	records []kafka.Message
+}
+
+func (tr *fakeKafkaTransport) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	switch req := req.(type) {
+	case *metadata.Request:
+		return &metadata.Response{
+			Brokers: []metadata.ResponseBroker{{NodeID: 1, Host: "127.0.0.1", Port: 9092}},
+			Topics: []metadata.ResponseTopic{{
+				Name: tr.topic,
+				Partitions: []metadata.ResponsePartition{
+					{PartitionIndex: 0, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}},
+					{PartitionIndex: 1, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}},
+				},
+			}},
+		}, nil
+	case *produce.Request:
+		res := &produce.Response{}
+		for _, t := range req.Topics {
+			rt := produce.ResponseTopic{Topic: t.Topic}
+			for _, p := range t.Partitions {
+				err := tr.read(t.Topic, int(p.Partition), p.RecordSet.Records)
+				if err != nil {
+					return nil, err
+				}
+				rt.Partitions = append(rt.Partitions, produce.ResponsePartition{Partition: p.Partition})
+			}
+			res.Topics = append(res.Topics, rt)
+		}
+		return res, nil
+	default:
+		return nil, errors.Errorf("unexpected kafka request %T", req)
+	}
+}
+
+func (tr *fakeKafkaTransport) read(topic string, partition int, records protocol.RecordReader) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for {
+		r, err := records.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		m := kafka.Message{Topic: topic, Partition: partition}
+		if m.Key, err = protocol.ReadAll(r.Key); err != nil {
+			return err
+		}
+		if m.Value, err = protocol.ReadAll(r.Value); err != nil {
+			return err
+		}
+		for _, h := range r.Headers {
+			m.Headers = append(m.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+		}
+		tr.records = append(tr.records, m)
+	}
+}
+
+func newTestKafka(t *testing.T, mode string) (Publisher, *fakeKafkaTransport) {
+	t.Helper()
+	p, err := NewKafka(newTestEncoder(t, mode), &KafkaConfig{
+		Brokers: []string{"127.0.0.1:9092"},
+		Topic:   "events",
+	})
+	if err != nil {
+		t.Fatalf("NewKafka() error = %v", err)
+	}
+	tr := &fakeKafkaTransport{topic: "events"}
+	p.(*kafkaPublisher).writer.Transport = tr
+	t.Cleanup(func() { _ = p.Close() })
+	return p, tr
+}
+
+func TestKafkaPublish(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		want map[string]string
+	}{
+		{
+			name: "binary",
+			mode: ModeBinary,
+			want: map[string]string{
+				"content-type":   ContentTypeJSON,
+				"ce_specversion": "1.0",
+				"ce_id":          "acme-42",
+				"ce_type":        "test.BookCreated",
+				"ce_source":      "/test",
+				"ce_tenantid":    "acme",
+			},
+		},
+		{
+			name: "structured",
+			mode: ModeStructured,
+			want: map[string]string{
+				"content-type": ContentTypeStructured,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, tr := newTestKafka(t, tt.mode)
+			e := testEvent("acme")
+			err := p.Publish(context.Background(), e)
+			if err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if len(tr.records) != 1 {
+				t.Fatalf("Publish() records = %d, want 1", len(tr.records))
+			}
+			m := tr.records[0]
+			if string(m.Key) != e.AggregateID {
+				t.Errorf("Publish() key = %q, want aggregate ID %q", m.Key, e.AggregateID)
+			}
+			headers := make(map[string]string, len(m.Headers))
+			for _, h := range m.Headers {
+				headers[h.Key] = string(h.Value)
+			}
+			for k := range headers {
+				// Structured events carry attributes in the value only
+				if tt.mode == ModeStructured && strings.HasPrefix(k, headerPrefixKafka) {
+					t.Errorf("Publish() header %s, want none of structured event", k)
+				}
+			}
+			for k, v := range tt.want {
+				if headers[k] != v {
+					t.Errorf("Publish() header %s = %q, want %q", k, headers[k], v)
+				}
+			}
+			if tt.mode == ModeBinary && string(m.Value) != string(e.Payload) {
+				t.Errorf("Publish() value = %s, want %s", m.Value, e.Payload)
+			}
+		})
+	}
+}
+
+func TestKafkaPublishPartitionsByAggregate(t *testing.T) {
+	p, tr := newTestKafka(t, ModeBinary)
+	// Events of an aggregate land in one partition, so that they stay in order
+	for i := 0; i < 4; i++ {
+		e := testEvent("")
+		e.ID += int64(i)
+		err := p.Publish(context.Background(), e)
+		if err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	for _, m := range tr.records {
+		if m.Partition != tr.records[0].Partition {
+			t.Fatalf("Publish() partitions of aggregate = %d and %d, want one", tr.records[0].Partition, m.Partition)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/outbox"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type logPublisher struct {
+	encoder *Encoder
+	log     *logrus.Entry
+}
+
+// Publish implements outbox.Publisher.
+func (p *logPublisher) Publish(ctx context.Context, event *outbox.Event) error {
+	ce := p.encoder.Event(event)
+	p.log.WithFields(logrus.Fields{
+		"event_id":         ce.ID,
+		"event_type":       ce.Type,
+		"event_source":     ce.Source,
+		"aggregate_id":     ce.Subject,
+		logger.FieldTenant: ce.TenantID,
+	}).Info("Event published")
+	return nil
+}
+
+// Close implements Publisher.
+func (p *logPublisher) Close() error {
+	return nil
+}
+
+// NewLog creates publisher logging event attributes, data is not logged.
+func NewLog(encoder *Encoder, logger *logrus.Logger) Publisher {
+	return &logPublisher{
+		encoder: encoder,
+		log:     logger.WithField("layer", "infrastructure.publisher.Log"),
+	}
+}
+
+type writerPublisher struct {
+	encoder *Encoder
+	mu      sync.Mutex
+	w       io.Writer
+}
+
+// Publish implements outbox.Publisher.
+func (p *writerPublisher) Publish(ctx context.Context, event *outbox.Event) error {
+	ce := p.encoder.Event(event)
+	b, err := json.Marshal(ce)
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal event [%s]", ce.ID)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(b, '\n'))
+	if err != nil {
+		return errors.Wrapf(err, "cannot write event [%s]", ce.ID)
+	}
+	return nil
+}
+
+// Close implements Publisher, w is not closed.
+func (p *writerPublisher) Close() error {
+	return nil
+}
+
+// NewWriter creates publisher writing structured events as JSON lines to w,
+// e.g. os.Stdout.
+func NewWriter(encoder *Encoder, w io.Writer) Publisher {
+	return &writerPublisher{
+		encoder: encoder,
+		w:       w,
+	}
+}
@@ -0,0 +1,109 @@
+package publisher
+
+import (
+	"context"
+	"goapptemplate/pkg/outbox"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// headerPrefixNATS prefixes binary mode attributes of NATS binding
+const headerPrefixNATS = "ce-"
+
+type NATSConfig struct {
+	URL string
+	// Subject is prefix of subjects events are published to, followed by event
+	// type, e.g. events.BookCreated.
+	Subject string
+	// JetStream publishes waiting for stream acknowledgement, event ID is used as
+	// message ID, so that the stream deduplicates retried events.
+	JetStream bool
+}
+
+type natsPublisher struct {
+	encoder *Encoder
+	config  *NATSConfig
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+}
+
+// Publish implements outbox.Publisher. Without JetStream event is published once
+// server has processed it, it is lost when no subscriber listens.
+func (p *natsPublisher) Publish(ctx context.Context, event *outbox.Event) error {
+	m, err := p.encoder.Encode(event, headerPrefixNATS)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(p.config.Subject + "." + event.Type)
+	msg.Data = m.Body
+	for k, v := range m.Headers {
+		msg.Header.Set(k, v)
+	}
+	msg.Header.Set("Content-Type", m.ContentType)
+	if p.js != nil {
+		msg.Header.Set(nats.MsgIdHdr, m.ID)
+		_, err = p.js.PublishMsg(msg, nats.Context(ctx))
+		if err != nil {
+			return errors.Wrapf(err, "cannot publish event [%s] to stream", m.ID)
+		}
+		return nil
+	}
+	err = p.conn.PublishMsg(msg)
+	if err != nil {
+		return errors.Wrapf(err, "cannot publish event [%s]", m.ID)
+	}
+	// FlushWithContext refuses context without deadline, Flush waits for the
+	// default timeout of connection then
+	if _, ok := ctx.Deadline(); ok {
+		err = p.conn.FlushWithContext(ctx)
+	} else {
+		err = p.conn.Flush()
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cannot flush event [%s]", m.ID)
+	}
+	return nil
+}
+
+// Close implements Publisher.
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// NewNATS connects to NATS server, connection is re-established by client.
+func NewNATS(encoder *Encoder, config *NATSConfig, logger *logrus.Logger) (Publisher, error) {
+	if config.Subject == "" {
+		return nil, errors.New("nats subject is required")
+	}
+	log := logger.WithField("layer", "infrastructure.publisher.NATS")
+	conn, err := nats.Connect(
+		config.URL,
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.WithError(err).Warn("NATS disconnected")
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			log.WithField("url", c.ConnectedUrlRedacted()).Info("NATS reconnected")
+		}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot connect to nats")
+	}
+	p := &natsPublisher{
+		encoder: encoder,
+		config:  config,
+		conn:    conn,
+	}
+	if config.JetStream {
+		p.js, err = conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "cannot create jetstream context")
+		}
+	}
+	return p, nil
+}
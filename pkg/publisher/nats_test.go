@@ -0,0 +1,166 @@
+package publisher
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestNATSServer runs embedded NATS server with JetStream, shut down on cleanup.
+func newTestNATSServer(t *testing.T) *server.Server {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("cannot create nats server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newTestNATS(t *testing.T, s *server.Server, mode string, jetStream bool) Publisher {
+	t.Helper()
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	p, err := NewNATS(newTestEncoder(t, mode), &NATSConfig{
+		URL:       s.ClientURL(),
+		Subject:   "events",
+		JetStream: jetStream,
+	}, l)
+	if err != nil {
+		t.Fatalf("NewNATS() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestNATSPublish(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		want map[string]string
+	}{
+		{
+			name: "binary",
+			mode: ModeBinary,
+			want: map[string]string{
+				"Content-Type": ContentTypeJSON,
+				"ce-id":        "acme-42",
+				"ce-type":      "test.BookCreated",
+				"ce-tenantid":  "acme",
+			},
+		},
+		{
+			name: "structured",
+			mode: ModeStructured,
+			want: map[string]string{
+				"Content-Type": ContentTypeStructured,
+				"ce-id":        "",
+			},
+		},
+	}
+	s := newTestNATSServer(t)
+	conn, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("cannot connect to nats: %v", err)
+	}
+	defer conn.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := conn.SubscribeSync("events.>")
+			if err != nil {
+				t.Fatalf("cannot subscribe: %v", err)
+			}
+			defer sub.Unsubscribe()
+			p := newTestNATS(t, s, tt.mode, false)
+
+			e := testEvent("acme")
+			err = p.Publish(context.Background(), e)
+			if err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			msg, err := sub.NextMsg(5 * time.Second)
+			if err != nil {
+				t.Fatalf("cannot receive event: %v", err)
+			}
+			if msg.Subject != "events.BookCreated" {
+				t.Errorf("Publish() subject = %q, want %q", msg.Subject, "events.BookCreated")
+			}
+			for k, v := range tt.want {
+				if got := msg.Header.Get(k); got != v {
+					t.Errorf("Publish() header %s = %q, want %q", k, got, v)
+				}
+			}
+			if tt.mode == ModeBinary && string(msg.Data) != string(e.Payload) {
+				t.Errorf("Publish() data = %s, want %s", msg.Data, e.Payload)
+			}
+		})
+	}
+}
+
+func TestNATSPublishJetStreamDeduplicates(t *testing.T) {
+	s := newTestNATSServer(t)
+	conn, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("cannot connect to nats: %v", err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatalf("cannot create jetstream context: %v", err)
+	}
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:       "EVENTS",
+		Subjects:   []string{"events.>"},
+		Duplicates: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("cannot create stream: %v", err)
+	}
+	p := newTestNATS(t, s, ModeBinary, true)
+
+	// Relay publishes event again when it fails to mark it published
+	e := testEvent("acme")
+	for i := 0; i < 2; i++ {
+		err = p.Publish(context.Background(), e)
+		if err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	other := testEvent("acme")
+	other.ID++
+	err = p.Publish(context.Background(), other)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	info, err := js.StreamInfo("EVENTS")
+	if err != nil {
+		t.Fatalf("cannot get stream info: %v", err)
+	}
+	if info.State.Msgs != 2 {
+		t.Fatalf("stream messages = %d, want 2", info.State.Msgs)
+	}
+	msg, err := js.GetMsg("EVENTS", 1)
+	if err != nil {
+		t.Fatalf("cannot get stream message: %v", err)
+	}
+	if got := msg.Header.Get(nats.MsgIdHdr); got != "acme-42" {
+		t.Fatalf("message ID = %q, want %q", got, "acme-42")
+	}
+}
@@ -0,0 +1,22 @@
+package publisher
+
+import (
+	"goapptemplate/pkg/outbox"
+)
+
+const (
+	TypeLog     = "log"
+	TypeStdout  = "stdout"
+	TypeWebhook = "webhook"
+	TypeNATS    = "nats"
+	TypeKafka   = "kafka"
+	TypeAMQP    = "amqp"
+)
+
+// Publisher delivers outbox events as CloudEvents to a broker or endpoint. Publish
+// returns nil only once the broker accepted the event, see outbox.Publisher.
+type Publisher interface {
+	outbox.Publisher
+	// Close releases connections, events are not published after.
+	Close() error
+}
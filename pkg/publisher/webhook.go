@@ -0,0 +1,73 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"goapptemplate/pkg/outbox"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// headerPrefixHTTP prefixes binary mode attributes of HTTP binding
+const headerPrefixHTTP = "ce-"
+
+type WebhookConfig struct {
+	URL string
+	// Headers are added to every request, e.g. Authorization.
+	Headers map[string]string
+}
+
+type webhookPublisher struct {
+	encoder *Encoder
+	config  *WebhookConfig
+	client  *http.Client
+}
+
+// Publish implements outbox.Publisher, event is published when endpoint responds
+// with 2xx status.
+func (p *webhookPublisher) Publish(ctx context.Context, event *outbox.Event) error {
+	m, err := p.encoder.Encode(event, headerPrefixHTTP)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(m.Body))
+	if err != nil {
+		return errors.Wrap(err, "cannot create request")
+	}
+	for k, v := range p.config.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range m.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", m.ContentType)
+	res, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "cannot send request")
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status [%s]", res.Status)
+	}
+	return nil
+}
+
+// Close implements Publisher.
+func (p *webhookPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// NewWebhook creates publisher posting events to URL with HTTP binding.
+func NewWebhook(encoder *Encoder, config *WebhookConfig) (Publisher, error) {
+	if config.URL == "" {
+		return nil, errors.New("webhook url is required")
+	}
+	return &webhookPublisher{
+		encoder: encoder,
+		config:  config,
+		client:  &http.Client{},
+	}, nil
+}
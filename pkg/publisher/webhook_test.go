@@ -0,0 +1,92 @@
+package publisher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		status  int
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "binary",
+			mode:   ModeBinary,
+			status: http.StatusNoContent,
+			want: map[string]string{
+				"Authorization":  "Bearer token",
+				"Content-Type":   ContentTypeJSON,
+				"Ce-Specversion": "1.0",
+				"Ce-Id":          "acme-42",
+				"Ce-Type":        "test.BookCreated",
+				"Ce-Source":      "/test",
+				"Ce-Tenantid":    "acme",
+			},
+		},
+		{
+			name:   "structured",
+			mode:   ModeStructured,
+			status: http.StatusAccepted,
+			want: map[string]string{
+				"Authorization": "Bearer token",
+				"Content-Type":  ContentTypeStructured,
+				"Ce-Id":         "",
+			},
+		},
+		{
+			name:    "server error",
+			mode:    ModeBinary,
+			status:  http.StatusServiceUnavailable,
+			wantErr: true,
+		},
+		{
+			name:    "not modified",
+			mode:    ModeBinary,
+			status:  http.StatusNotModified,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				header http.Header
+				body   []byte
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			p, err := NewWebhook(newTestEncoder(t, tt.mode), &WebhookConfig{
+				URL:     srv.URL,
+				Headers: map[string]string{"Authorization": "Bearer token"},
+			})
+			if err != nil {
+				t.Fatalf("NewWebhook() error = %v", err)
+			}
+			defer p.Close()
+
+			e := testEvent("acme")
+			err = p.Publish(context.Background(), e)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for k, v := range tt.want {
+				if got := header.Get(k); got != v {
+					t.Errorf("Publish() header %s = %q, want %q", k, got, v)
+				}
+			}
+			if tt.mode == ModeBinary && string(body) != string(e.Payload) {
+				t.Errorf("Publish() body = %s, want %s", body, e.Payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AlgorithmTokenBucket allows bursts up to Burst requests and refills
+	// Requests tokens per Window evenly
+	AlgorithmTokenBucket = "token_bucket"
+	// AlgorithmSlidingWindow allows Requests per Window, previous window
+	// requests are weighted by its overlap with the sliding window
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+var ErrAlgorithm = errors.New("unknown rate limit algorithm")
+
+// Limit is the number of requests allowed per window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+	// Burst is token bucket capacity, Requests when zero
+	Burst int
+}
+
+// String formats limit as "requests/window".
+func (l Limit) String() string {
+	return strconv.Itoa(l.Requests) + "/" + l.Window.String()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// rate returns tokens refilled per nanosecond.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / float64(l.Window)
+}
+
+// ParseLimit parses limit formatted as "requests/window", e.g. "100/1m".
+func ParseLimit(s string) (Limit, error) {
+	requests, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, errors.Errorf("invalid limit [%s], expected requests/window", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return Limit{}, errors.Errorf("invalid limit [%s] requests", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return Limit{}, errors.Errorf("invalid limit [%s] window", s)
+	}
+	return Limit{Requests: n, Window: d}, nil
+}
+
+// Result of a single request accounting.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of requests allowed right away
+	Remaining int
+	// Reset is the duration until the limit is fully restored
+	Reset time.Duration
+	// RetryAfter is the duration until the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Limiter accounts requests of keys.
+type Limiter interface {
+	// Allow consumes a single request of key when limit is not exceeded.
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// tokenBucketResult builds result from tokens left in bucket after accounting.
+func tokenBucketResult(limit Limit, allowed bool, tokens float64) *Result {
+	rate := limit.rate()
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     limit.burst(),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration(math.Ceil((float64(limit.burst()) - tokens) / rate)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+	}
+	return res
+}
+
+// slidingWindowResult builds result from previous and current window counters
+// after accounting, elapsed is the time passed since current window start.
+func slidingWindowResult(limit Limit, allowed bool, prev, curr int, elapsed time.Duration) *Result {
+	w := float64(limit.Window)
+	weight := (w - float64(elapsed)) / w
+	used := float64(prev)*weight + float64(curr)
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(0, limit.Requests-int(math.Ceil(used))),
+		Reset:     limit.Window - elapsed,
+	}
+	if allowed {
+		return res
+	}
+	// Next request is allowed once weighted usage drops to Requests-1
+	free := float64(limit.Requests - 1)
+	switch {
+	case float64(curr) <= free && prev > 0:
+		// Within current window, previous window weight decreases over time
+		res.RetryAfter = time.Duration(math.Ceil(w*weight - (free-float64(curr))*w/float64(prev)))
+	case curr > 0:
+		// Current window becomes the previous one, its weight decreases over time
+		res.RetryAfter = limit.Window - elapsed + time.Duration(math.Ceil(w*(1-free/float64(curr))))
+	default:
+		res.RetryAfter = limit.Window - elapsed
+	}
+	if res.RetryAfter <= 0 {
+		res.RetryAfter = time.Millisecond
+	}
+	return res
+}
+
+// FallbackLimiter uses fallback limiter for cooldown duration after primary limiter failure,
+// so that requests are still limited per instance while shared storage is unavailable.
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	cooldown time.Duration
+	// unavailableUntil is unix nano time until which primary limiter is bypassed
+	unavailableUntil atomic.Int64
+	log              *logrus.Entry
+}
+
+// Allow implements Limiter.
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	if time.Now().UnixNano() < l.unavailableUntil.Load() {
+		return l.fallback.Allow(ctx, key, limit)
+	}
+	res, err := l.primary.Allow(ctx, key, limit)
+	if err == nil {
+		return res, nil
+	}
+	l.unavailableUntil.Store(time.Now().Add(l.cooldown).UnixNano())
+	l.log.WithError(err).WithField("cooldown", l.cooldown.String()).Warn("primary rate limiter is bypassed")
+	return l.fallback.Allow(ctx, key, limit)
+}
+
+func NewFallbackLimiter(primary Limiter, fallback Limiter, cooldown time.Duration, logger *logrus.Logger) *FallbackLimiter {
+	return &FallbackLimiter{
+		primary:  primary,
+		fallback: fallback,
+		cooldown: cooldown,
+		log:      logger.WithField("layer", "infrastructure.ratelimit.FallbackLimiter"),
+	}
+}
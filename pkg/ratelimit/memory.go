@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type memoryState struct {
+	// Token bucket tokens or sliding window current counter
+	tokens float64
+	// Sliding window previous counter
+	prev int
+	// ts is the last refill time or current window start
+	ts time.Time
+	// exp is the time state can be collected after
+	exp time.Time
+}
+
+// MemoryLimiter keeps limits state in process memory, limits are per instance.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	algorithm string
+	states    map[string]*memoryState
+	done      chan struct{}
+	once      sync.Once
+}
+
+// Allow implements Limiter.
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.states[key]
+	if !ok {
+		s = &memoryState{}
+		l.states[key] = s
+	}
+	switch l.algorithm {
+	case AlgorithmTokenBucket:
+		burst := float64(limit.burst())
+		if !ok {
+			s.tokens = burst
+			s.ts = now
+		}
+		s.tokens = math.Min(burst, s.tokens+float64(now.Sub(s.ts))*limit.rate())
+		s.ts = now
+		allowed := s.tokens >= 1
+		if allowed {
+			s.tokens--
+		}
+		s.exp = now.Add(time.Duration((burst - s.tokens) / limit.rate()))
+		return tokenBucketResult(limit, allowed, s.tokens), nil
+	case AlgorithmSlidingWindow:
+		start := now.Truncate(limit.Window)
+		switch {
+		case start.Equal(s.ts):
+		case start.Sub(s.ts) == limit.Window:
+			s.prev, s.tokens = int(s.tokens), 0
+		default:
+			s.prev, s.tokens = 0, 0
+		}
+		s.ts = start
+		elapsed := now.Sub(start)
+		used := float64(s.prev)*float64(limit.Window-elapsed)/float64(limit.Window) + s.tokens
+		allowed := used+1 <= float64(limit.Requests)
+		if allowed {
+			s.tokens++
+		}
+		s.exp = start.Add(2 * limit.Window)
+		return slidingWindowResult(limit, allowed, s.prev, int(s.tokens), elapsed), nil
+	default:
+		return nil, ErrAlgorithm
+	}
+}
+
+// Close stops expired states collection.
+func (l *MemoryLimiter) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *MemoryLimiter) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			for key, s := range l.states {
+				if now.After(s.exp) {
+					delete(l.states, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// NewMemoryLimiter creates limiter using algorithm, states of idle keys are collected every gcInterval.
+func NewMemoryLimiter(algorithm string, gcInterval time.Duration) (*MemoryLimiter, error) {
+	if algorithm != AlgorithmTokenBucket && algorithm != AlgorithmSlidingWindow {
+		return nil, errors.Wrapf(ErrAlgorithm, "[%s]", algorithm)
+	}
+	// States are not bounded otherwise
+	if gcInterval <= 0 {
+		return nil, errors.Errorf("invalid gc interval [%s]", gcInterval)
+	}
+	l := &MemoryLimiter{
+		algorithm: algorithm,
+		states:    make(map[string]*memoryState),
+		done:      make(chan struct{}),
+	}
+	go l.gc(gcInterval)
+	return l, nil
+}
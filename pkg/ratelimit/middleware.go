@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"goapptemplate/pkg/logger"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = fiber.HeaderRetryAfter
+)
+
+// Group is a path prefix with its own limit.
+type Group struct {
+	Path  string
+	Limit Limit
+}
+
+type Config struct {
+	Limiter Limiter
+	// Limit of requests not matching Groups.
+	Limit Limit
+	// Groups are limited separately, the longest matching prefix wins.
+	Groups []Group
+	// Key returns client identity requests are accounted by, client IP by default.
+	Key func(c *fiber.Ctx) string
+	// Principals are limits of particular client identities, take precedence over group limits.
+	Principals map[string]Limit
+	// Next skips rate limiting when returns true.
+	Next func(c *fiber.Ctx) bool
+}
+
+// Middleware limits requests rate per client and route group.
+//
+// Requests are allowed when limiter fails, so that rate limiting storage
+// outage does not make the service unavailable.
+type Middleware struct {
+	config *Config
+	log    *logrus.Entry
+}
+
+// Handler returns fiber middleware.
+func (m *Middleware) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.config.Next != nil && m.config.Next(c) {
+			return c.Next()
+		}
+		client := m.config.Key(c)
+		group, limit := m.match(c.Path())
+		if l, ok := m.config.Principals[client]; ok {
+			limit = l
+		}
+		// Identity is hashed to keep API keys and credentials out of storage
+		res, err := m.config.Limiter.Allow(c.UserContext(), group+"|"+hash(client), limit)
+		if err != nil {
+			logger.Ctx(c.UserContext(), m.log).WithError(err).Warn("cannot limit request rate")
+			return c.Next()
+		}
+		c.Set(HeaderLimit, strconv.Itoa(res.Limit))
+		c.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+		c.Set(HeaderReset, seconds(res.Reset))
+		c.Set(HeaderPolicy, strconv.Itoa(limit.Requests)+";w="+seconds(limit.Window))
+		if !res.Allowed {
+			c.Set(HeaderRetryAfter, seconds(res.RetryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.ErrTooManyRequests)
+		}
+		return c.Next()
+	}
+}
+
+// match returns group path belongs to and its limit.
+func (m *Middleware) match(path string) (string, Limit) {
+	group, limit := "", m.config.Limit
+	for _, g := range m.config.Groups {
+		if strings.HasPrefix(path, g.Path) && len(g.Path) > len(group) {
+			group, limit = g.Path, g.Limit
+		}
+	}
+	return group, limit
+}
+
+// seconds formats duration as a whole number of seconds rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:12])
+}
+
+func New(config *Config, logger *logrus.Logger) *Middleware {
+	if config.Key == nil {
+		config.Key = func(c *fiber.Ctx) string {
+			return c.IP()
+		}
+	}
+	return &Middleware{
+		config: config,
+		log:    logger.WithField("layer", "infrastructure.ratelimit.Middleware"),
+	}
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// Scripts use Redis server time, so that instances with skewed clocks share limits consistently.
+
+// tokenBucketScript returns whether request is allowed and tokens left.
+//
+// KEYS[1] - bucket key, ARGV[1] - tokens refilled per microsecond, ARGV[2] - bucket capacity
+var tokenBucketScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', string.format('%d', now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate / 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript returns whether request is allowed, previous and current
+// window counters and milliseconds elapsed since current window start.
+//
+// KEYS[1] - window key, ARGV[1] - window milliseconds, ARGV[2] - requests per window
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local w = math.floor(now / window)
+local state = redis.call('HMGET', KEYS[1], 'w', 'curr', 'prev')
+local sw = tonumber(state[1]) or w
+local curr = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+if sw == w - 1 then
+	prev = curr
+	curr = 0
+elseif sw ~= w then
+	prev = 0
+	curr = 0
+end
+local elapsed = now - w * window
+local allowed = 0
+if prev * (window - elapsed) / window + curr + 1 <= limit then
+	curr = curr + 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'w', w, 'curr', curr, 'prev', prev)
+redis.call('PEXPIRE', KEYS[1], 2 * window)
+return {allowed, prev, curr, elapsed}
+`)
+
+// RedisLimiter keeps limits state in Redis, limits are shared by all instances.
+type RedisLimiter struct {
+	client    redis.Scripter
+	algorithm string
+	prefix    string
+}
+
+// Allow implements Limiter.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	key = l.prefix + key
+	switch l.algorithm {
+	case AlgorithmTokenBucket:
+		rate := limit.rate() * float64(time.Microsecond)
+		res, err := tokenBucketScript.Run(
+			ctx, l.client, []string{key},
+			strconv.FormatFloat(rate, 'g', -1, 64), limit.burst(),
+		).Slice()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot run token bucket script of [%s]", key)
+		}
+		if len(res) != 2 {
+			return nil, errors.Errorf("unexpected token bucket script result %v", res)
+		}
+		tokens, err := strconv.ParseFloat(res[1].(string), 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse tokens")
+		}
+		return tokenBucketResult(limit, res[0].(int64) == 1, tokens), nil
+	case AlgorithmSlidingWindow:
+		res, err := slidingWindowScript.Run(
+			ctx, l.client, []string{key},
+			limit.Window.Milliseconds(), limit.Requests,
+		).Int64Slice()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot run sliding window script of [%s]", key)
+		}
+		if len(res) != 4 {
+			return nil, errors.Errorf("unexpected sliding window script result %v", res)
+		}
+		return slidingWindowResult(
+			limit, res[0] == 1, int(res[1]), int(res[2]),
+			time.Duration(res[3])*time.Millisecond,
+		), nil
+	default:
+		return nil, ErrAlgorithm
+	}
+}
+
+// NewRedisLimiter creates limiter using algorithm, state keys are prefixed with prefix.
+func NewRedisLimiter(client redis.Scripter, algorithm string, prefix string) (*RedisLimiter, error) {
+	if algorithm != AlgorithmTokenBucket && algorithm != AlgorithmSlidingWindow {
+		return nil, errors.Wrapf(ErrAlgorithm, "[%s]", algorithm)
+	}
+	return &RedisLimiter{
+		client:    client,
+		algorithm: algorithm,
+		prefix:    prefix,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package security
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/pkg/errors"
+)
+
+// CORS is cross-origin resource sharing policy.
+type CORS struct {
+	// AllowOrigins are allowed origins, e.g. "https://example.com", subdomains
+	// are matched with a leading wildcard "https://*.example.com", "*" allows any.
+	// Cross-origin requests are not allowed when empty.
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	// MaxAge is the duration preflight results are cached for.
+	MaxAge time.Duration
+}
+
+// Validate reports misconfigured policy.
+func (c *CORS) Validate() error {
+	for _, o := range c.AllowOrigins {
+		if o == "*" {
+			if c.AllowCredentials {
+				return errors.New("any origin cannot be allowed with credentials")
+			}
+			continue
+		}
+		err := validateOrigin(o)
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range c.AllowMethods {
+		switch m {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodPost, fiber.MethodPut,
+			fiber.MethodPatch, fiber.MethodDelete, fiber.MethodOptions:
+		default:
+			return errors.Errorf("invalid method [%s]", m)
+		}
+	}
+	for _, h := range append(c.AllowHeaders, c.ExposeHeaders...) {
+		if !isToken(h) {
+			return errors.Errorf("invalid header [%s]", h)
+		}
+	}
+	if c.MaxAge < 0 {
+		return errors.Errorf("invalid max age [%s]", c.MaxAge)
+	}
+	return nil
+}
+
+// Handler returns fiber middleware.
+func (c *CORS) Handler() fiber.Handler {
+	if len(c.AllowOrigins) == 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	return cors.New(cors.Config{
+		AllowOrigins:     strings.Join(c.AllowOrigins, ","),
+		AllowMethods:     strings.Join(c.AllowMethods, ","),
+		AllowHeaders:     strings.Join(c.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(c.ExposeHeaders, ","),
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           int(c.MaxAge.Seconds()),
+	})
+}
+
+// validateOrigin accepts scheme://host[:port] origins, host may start with "*." wildcard.
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return errors.Wrapf(err, "invalid origin [%s]", origin)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid origin [%s] scheme", origin)
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return errors.Errorf("invalid origin [%s], expected scheme://host[:port]", origin)
+	}
+	host := strings.TrimPrefix(u.Hostname(), "*.")
+	if host == "" || strings.Contains(host, "*") {
+		return errors.Errorf("invalid origin [%s] wildcard, only leading subdomain wildcard is supported", origin)
+	}
+	return nil
+}
+
+// isToken reports whether s is a valid header name.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		alnum := (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !alnum && !strings.ContainsRune("!#$%&'*+-.^_`|~", r) {
+			return false
+		}
+	}
+	return true
+}
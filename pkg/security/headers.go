@@ -0,0 +1,84 @@
+package security
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/helmet/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	FrameOptionsDeny       = "DENY"
+	FrameOptionsSameOrigin = "SAMEORIGIN"
+
+	// hstsPreloadMinAge is the minimal max-age accepted by browsers preload lists
+	hstsPreloadMinAge = 365 * 24 * time.Hour
+)
+
+var cspDirective = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+// Headers are security response headers.
+type Headers struct {
+	// ContentSecurityPolicy is not sent when empty.
+	ContentSecurityPolicy string
+	// CSPReportOnly reports policy violations instead of enforcing the policy.
+	CSPReportOnly bool
+	// HSTSMaxAge is Strict-Transport-Security max-age, the header is sent
+	// over HTTPS only and is not sent when zero.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// FrameOptions is [DENY|SAMEORIGIN].
+	FrameOptions   string
+	ReferrerPolicy string
+}
+
+// Validate reports misconfigured headers.
+func (h *Headers) Validate() error {
+	if h.ContentSecurityPolicy != "" {
+		for _, d := range strings.Split(h.ContentSecurityPolicy, ";") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+			name, _, _ := strings.Cut(d, " ")
+			if !cspDirective.MatchString(name) {
+				return errors.Errorf("invalid content security policy directive [%s]", d)
+			}
+		}
+	}
+	if h.HSTSMaxAge < 0 {
+		return errors.Errorf("invalid hsts max age [%s]", h.HSTSMaxAge)
+	}
+	if h.HSTSPreload && (h.HSTSMaxAge < hstsPreloadMinAge || !h.HSTSIncludeSubdomains) {
+		return errors.Errorf("hsts preload requires max age of at least [%s] and subdomains", hstsPreloadMinAge)
+	}
+	switch h.FrameOptions {
+	case FrameOptionsDeny, FrameOptionsSameOrigin:
+	default:
+		return errors.Errorf("invalid frame options [%s]", h.FrameOptions)
+	}
+	switch h.ReferrerPolicy {
+	case "no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+		"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url":
+	default:
+		return errors.Errorf("invalid referrer policy [%s]", h.ReferrerPolicy)
+	}
+	return nil
+}
+
+// Handler returns fiber middleware.
+func (h *Headers) Handler() fiber.Handler {
+	return helmet.New(helmet.Config{
+		ContentSecurityPolicy: h.ContentSecurityPolicy,
+		CSPReportOnly:         h.CSPReportOnly,
+		HSTSMaxAge:            int(h.HSTSMaxAge.Seconds()),
+		HSTSExcludeSubdomains: !h.HSTSIncludeSubdomains,
+		HSTSPreloadEnabled:    h.HSTSPreload,
+		XFrameOptions:         h.FrameOptions,
+		ReferrerPolicy:        h.ReferrerPolicy,
+	})
+}
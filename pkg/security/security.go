@@ -0,0 +1,108 @@
+package security
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// Group is a path prefix with its own policy.
+type Group struct {
+	Path    string
+	CORS    CORS
+	Headers Headers
+}
+
+type Config struct {
+	// CORS and Headers of requests not matching Groups.
+	CORS    CORS
+	Headers Headers
+	// Groups override policy of path prefixes, the longest matching prefix wins.
+	Groups []Group
+}
+
+// Validate reports misconfigured policies.
+func (c *Config) Validate() error {
+	err := c.CORS.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid cors")
+	}
+	err = c.Headers.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid headers")
+	}
+	for i := range c.Groups {
+		g := &c.Groups[i]
+		err = g.CORS.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid [%s] cors", g.Path)
+		}
+		err = g.Headers.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid [%s] headers", g.Path)
+		}
+	}
+	return nil
+}
+
+type handlers struct {
+	path    string
+	cors    fiber.Handler
+	headers fiber.Handler
+}
+
+// Middleware applies CORS policy and security headers of the group request belongs to.
+type Middleware struct {
+	def    handlers
+	groups []handlers
+}
+
+// CORS returns fiber middleware applying CORS policy.
+func (m *Middleware) CORS() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return m.match(c.Path()).cors(c)
+	}
+}
+
+// Headers returns fiber middleware setting security headers.
+func (m *Middleware) Headers() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return m.match(c.Path()).headers(c)
+	}
+}
+
+func (m *Middleware) match(path string) *handlers {
+	h := &m.def
+	for i := range m.groups {
+		g := &m.groups[i]
+		if strings.HasPrefix(path, g.path) && len(g.path) > len(h.path) {
+			h = g
+		}
+	}
+	return h
+}
+
+// New validates config and creates middleware.
+func New(config *Config) (*Middleware, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	m := &Middleware{
+		def: handlers{
+			cors:    config.CORS.Handler(),
+			headers: config.Headers.Handler(),
+		},
+		groups: make([]handlers, 0, len(config.Groups)),
+	}
+	for i := range config.Groups {
+		g := &config.Groups[i]
+		m.groups = append(m.groups, handlers{
+			path:    g.Path,
+			cors:    g.CORS.Handler(),
+			headers: g.Headers.Handler(),
+		})
+	}
+	return m, nil
+}
@@ -0,0 +1,94 @@
+package sticky
+
+import (
+	"goapptemplate/pkg/postgres"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Config struct {
+	// TTL is the duration reads of the client stick to primary after its write.
+	TTL time.Duration
+	// Cookie carries time reads stick to primary until, unix milliseconds.
+	Cookie string
+	// Header carries the same time for clients not keeping cookies, clients
+	// send back header value of the last write response.
+	Header string
+	// Next skips stickiness when returns true.
+	Next func(c *fiber.Ctx) bool
+}
+
+// Middleware provides read your writes consistency with read replicas. Successful
+// writes mark the client with time its reads go to primary until, through cookie
+// and response header. Requests of marked clients carry postgres.WithPrimary context.
+type Middleware struct {
+	config *Config
+}
+
+// Handler returns fiber middleware.
+func (m *Middleware) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.config.Next != nil && m.config.Next(c) {
+			return c.Next()
+		}
+		now := time.Now()
+		if m.sticks(c, now) {
+			c.SetUserContext(postgres.WithPrimary(c.UserContext()))
+		}
+		err := c.Next()
+		if err != nil || !write(c.Method()) || c.Response().StatusCode() >= fiber.StatusBadRequest {
+			return err
+		}
+		until := now.Add(m.config.TTL)
+		value := strconv.FormatInt(until.UnixMilli(), 10)
+		if m.config.Cookie != "" {
+			c.Cookie(&fiber.Cookie{
+				Name:     m.config.Cookie,
+				Value:    value,
+				Path:     "/",
+				Expires:  until,
+				HTTPOnly: true,
+				SameSite: fiber.CookieSameSiteLaxMode,
+			})
+		}
+		if m.config.Header != "" {
+			c.Set(m.config.Header, value)
+		}
+		return nil
+	}
+}
+
+// sticks reports whether client wrote within TTL, times further than TTL ahead
+// are not trusted.
+func (m *Middleware) sticks(c *fiber.Ctx, now time.Time) bool {
+	for _, v := range []string{c.Cookies(m.config.Cookie), c.Get(m.config.Header)} {
+		if v == "" {
+			continue
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		until := time.UnixMilli(ms)
+		if now.Before(until) && !until.After(now.Add(m.config.TTL)) {
+			return true
+		}
+	}
+	return false
+}
+
+func write(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return false
+	}
+	return true
+}
+
+func New(config *Config) *Middleware {
+	return &Middleware{
+		config: config,
+	}
+}
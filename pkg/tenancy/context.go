@@ -0,0 +1,16 @@
+package tenancy
+
+import "context"
+
+type ctxKey struct{}
+
+// WithTenant returns a copy of ctx carrying tenant ID.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenantID)
+}
+
+// FromContext returns tenant ID stored in ctx, empty when request is not tenant scoped.
+func FromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(ctxKey{}).(string)
+	return tenantID
+}
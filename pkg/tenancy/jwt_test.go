@@ -0,0 +1,125 @@
+package tenancy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("secret")
+
+// sign returns JWT of header and claims signed with HMAC-SHA256 of secret.
+func sign(t *testing.T, header, claims map[string]interface{}, secret []byte) string {
+	t.Helper()
+	seg := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("cannot marshal token segment: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := seg(header) + "." + seg(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestClaim(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			token: sign(t, hs256, map[string]interface{}{"tenant": "acme"}, testSecret),
+			want:  "acme",
+		},
+		{
+			name: "valid within exp and nbf",
+			token: sign(t, hs256, map[string]interface{}{
+				"tenant": "acme",
+				"exp":    now.Add(time.Minute).Unix(),
+				"nbf":    now.Add(-time.Minute).Unix(),
+			}, testSecret),
+			want: "acme",
+		},
+		{
+			name:  "missing claim",
+			token: sign(t, hs256, map[string]interface{}{"sub": "user"}, testSecret),
+			want:  "",
+		},
+		{
+			name:  "non-string claim",
+			token: sign(t, hs256, map[string]interface{}{"tenant": 42}, testSecret),
+			want:  "",
+		},
+		{
+			name:    "bad signature",
+			token:   sign(t, hs256, map[string]interface{}{"tenant": "acme"}, []byte("other")),
+			wantErr: true,
+		},
+		{
+			name:    "alg none",
+			token:   sign(t, map[string]interface{}{"alg": "none"}, map[string]interface{}{"tenant": "acme"}, testSecret),
+			wantErr: true,
+		},
+		{
+			name:    "alg HS512",
+			token:   sign(t, map[string]interface{}{"alg": "HS512"}, map[string]interface{}{"tenant": "acme"}, testSecret),
+			wantErr: true,
+		},
+		{
+			name: "unsigned alg none",
+			token: func() string {
+				tok := sign(t, map[string]interface{}{"alg": "none"}, map[string]interface{}{"tenant": "acme"}, testSecret)
+				return tok[:len(tok)-43]
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   sign(t, hs256, map[string]interface{}{"tenant": "acme", "exp": now.Unix()}, testSecret),
+			wantErr: true,
+		},
+		{
+			name:    "not valid yet",
+			token:   sign(t, hs256, map[string]interface{}{"tenant": "acme", "nbf": now.Add(time.Second).Unix()}, testSecret),
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			token:   "a.b",
+			wantErr: true,
+		},
+		{
+			name:    "bad payload encoding",
+			token:   "eyJhbGciOiJIUzI1NiJ9.!!!.c2ln",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := claim(tt.token, testSecret, "tenant", now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrToken) {
+					t.Fatalf("claim() error = %v, want %v", err, ErrToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("claim() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("claim() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
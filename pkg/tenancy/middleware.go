@@ -0,0 +1,179 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goapptemplate/pkg/logger"
+	"goapptemplate/pkg/postgres"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Sources tenant ID is resolved from
+const (
+	SourceSubdomain = "subdomain"
+	SourceHeader    = "header"
+	SourceClaim     = "claim"
+)
+
+const LocalsTenant = "tenant"
+
+var (
+	ErrSource        = errors.New("unknown tenant source")
+	ErrUnknownTenant = errors.New("unknown tenant")
+)
+
+type Config struct {
+	// Source is one of SourceSubdomain, SourceHeader or SourceClaim.
+	Source string
+	// Header carries tenant ID with SourceHeader.
+	Header string
+	// Domain is the base domain tenant subdomains belong to with SourceSubdomain,
+	// the first host label is used when empty.
+	Domain string
+	// Claim of HS256 signed bearer token carrying tenant ID with SourceClaim.
+	Claim  string
+	Secret []byte
+	// Lookup validates tenant ID and returns tenant schema, ErrUnknownTenant is
+	// returned for unregistered tenants.
+	Lookup func(ctx context.Context, tenantID string) (string, error)
+	// RowLevel isolates tenants sharing schema by row level security, tenant ID is
+	// set for transactions instead of search path.
+	RowLevel bool
+	// CacheTTL is the duration looked up tenants are cached for, 0 disables cache.
+	CacheTTL time.Duration
+	// Next skips tenant resolution when returns true.
+	Next func(c *fiber.Ctx) bool
+}
+
+type cached struct {
+	schema  string
+	expires time.Time
+}
+
+// Middleware resolves request tenant, stores it in request user context with
+// Postgres search path of tenant schema, or tenant ID for row level security,
+// and adds it to request logger.
+//
+// Must be used after RequestLogger.
+type Middleware struct {
+	config *Config
+	mu     sync.RWMutex
+	cache  map[string]cached
+	log    *logrus.Entry
+}
+
+// Handler returns fiber middleware.
+func (m *Middleware) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if m.config.Next != nil && m.config.Next(c) {
+			return c.Next()
+		}
+		tenantID, err := m.resolve(c)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+		if tenantID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "tenant is required")
+		}
+		schema, err := m.lookup(c.UserContext(), tenantID)
+		if errors.Is(err, ErrUnknownTenant) {
+			return fiber.NewError(fiber.StatusNotFound, "tenant not found")
+		}
+		if err != nil {
+			logger.Ctx(c.UserContext(), m.log).WithError(err).WithField(logger.FieldTenant, tenantID).Error("cannot look up tenant")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.ErrServiceUnavailable)
+		}
+		c.Locals(LocalsTenant, tenantID)
+		ctx := WithTenant(c.UserContext(), tenantID)
+		if m.config.RowLevel {
+			ctx = postgres.WithTenantID(ctx, tenantID)
+		} else {
+			ctx = postgres.WithSearchPath(ctx, schema)
+		}
+		if entry, ok := logger.FromContext(ctx); ok {
+			ctx = logger.WithEntry(ctx, entry.WithField(logger.FieldTenant, tenantID))
+		}
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+func (m *Middleware) resolve(c *fiber.Ctx) (string, error) {
+	switch m.config.Source {
+	case SourceSubdomain:
+		host := strings.ToLower(c.Hostname())
+		if i := strings.LastIndexByte(host, ':'); i > 0 && !strings.Contains(host[i:], "]") {
+			host = host[:i]
+		}
+		if m.config.Domain == "" {
+			sub, _, ok := strings.Cut(host, ".")
+			if !ok {
+				return "", nil
+			}
+			return sub, nil
+		}
+		sub, ok := strings.CutSuffix(host, "."+m.config.Domain)
+		if !ok || strings.Contains(sub, ".") {
+			return "", nil
+		}
+		return sub, nil
+	case SourceClaim:
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok {
+			return "", nil
+		}
+		return claim(strings.TrimSpace(token), m.config.Secret, m.config.Claim, time.Now())
+	default:
+		return c.Get(m.config.Header), nil
+	}
+}
+
+func (m *Middleware) lookup(ctx context.Context, tenantID string) (string, error) {
+	if m.config.CacheTTL <= 0 {
+		return m.config.Lookup(ctx, tenantID)
+	}
+	m.mu.RLock()
+	t, ok := m.cache[tenantID]
+	m.mu.RUnlock()
+	if ok && time.Now().Before(t.expires) {
+		return t.schema, nil
+	}
+	schema, err := m.config.Lookup(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	// Expired entries are dropped on write, so that unknown tenants do not grow cache
+	now := time.Now()
+	for id, t := range m.cache {
+		if !now.Before(t.expires) {
+			delete(m.cache, id)
+		}
+	}
+	m.cache[tenantID] = cached{schema: schema, expires: now.Add(m.config.CacheTTL)}
+	m.mu.Unlock()
+	return schema, nil
+}
+
+func New(config *Config, logger *logrus.Logger) (*Middleware, error) {
+	switch config.Source {
+	case SourceHeader, SourceSubdomain:
+	case SourceClaim:
+		if len(config.Secret) == 0 {
+			return nil, fmt.Errorf("secret is required for [%s] tenant source", SourceClaim)
+		}
+	default:
+		return nil, fmt.Errorf("%w [%s]", ErrSource, config.Source)
+	}
+	return &Middleware{
+		config: config,
+		cache:  make(map[string]cached),
+		log:    logger.WithField("layer", "infrastructure.tenancy.Middleware"),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package tenancy
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestApp(t *testing.T, config *Config) *fiber.App {
+	t.Helper()
+	config.Lookup = func(ctx context.Context, tenantID string) (string, error) {
+		if tenantID != "acme" {
+			return "", ErrUnknownTenant
+		}
+		return "tenant_acme", nil
+	}
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	m, err := New(config, l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	f := fiber.New()
+	f.Use(m.Handler())
+	f.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(FromContext(c.UserContext()))
+	})
+	return f
+}
+
+func TestMiddlewareSubdomain(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain string
+		host   string
+		status int
+	}{
+		{name: "base domain", domain: "example.com", host: "acme.example.com", status: fiber.StatusOK},
+		{name: "base domain with port", domain: "example.com", host: "acme.example.com:8000", status: fiber.StatusOK},
+		{name: "base domain case", domain: "example.com", host: "ACME.Example.com", status: fiber.StatusOK},
+		{name: "base domain mismatch", domain: "example.com", host: "acme.example.org", status: fiber.StatusBadRequest},
+		{name: "base domain suffix only", domain: "example.com", host: "acmeexample.com", status: fiber.StatusBadRequest},
+		{name: "nested subdomain", domain: "example.com", host: "x.acme.example.com", status: fiber.StatusBadRequest},
+		{name: "base domain itself", domain: "example.com", host: "example.com", status: fiber.StatusBadRequest},
+		{name: "unknown tenant", domain: "example.com", host: "other.example.com", status: fiber.StatusNotFound},
+		{name: "first label", host: "acme.example.com:8000", status: fiber.StatusOK},
+		{name: "single label with port", host: "localhost:8000", status: fiber.StatusBadRequest},
+		{name: "ipv6 with port", host: "[::1]:8000", status: fiber.StatusBadRequest},
+		{name: "ipv6", host: "[::1]", status: fiber.StatusBadRequest},
+		{name: "ipv6 with base domain", domain: "example.com", host: "[::1]:8000", status: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestApp(t, &Config{Source: SourceSubdomain, Domain: tt.domain})
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			req.Host = tt.host
+			res, err := f.Test(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if tt.status == fiber.StatusOK {
+				b, _ := io.ReadAll(res.Body)
+				if string(b) != "acme" {
+					t.Fatalf("tenant = %q, want %q", b, "acme")
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareClaim(t *testing.T) {
+	hs256 := map[string]interface{}{"alg": "HS256"}
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "valid", authorization: "Bearer " + sign(t, hs256, map[string]interface{}{"tenant": "acme"}, testSecret), status: fiber.StatusOK},
+		{name: "bad signature", authorization: "Bearer " + sign(t, hs256, map[string]interface{}{"tenant": "acme"}, []byte("other")), status: fiber.StatusUnauthorized},
+		{name: "missing claim", authorization: "Bearer " + sign(t, hs256, map[string]interface{}{}, testSecret), status: fiber.StatusBadRequest},
+		{name: "not bearer", authorization: "Basic YWNtZTo=", status: fiber.StatusBadRequest},
+		{name: "missing", status: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestApp(t, &Config{Source: SourceClaim, Claim: "tenant", Secret: testSecret})
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+			res, err := f.Test(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Reloader keeps certificate loaded from cert and key files up to date,
+// files are polled for modifications, so that rotated certificates
+// are served without restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	// modified is the latest modification time of both files
+	modified time.Time
+	done     chan struct{}
+	once     sync.Once
+	log      *logrus.Entry
+}
+
+// GetCertificate implements tls.Config GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads certificate when files were modified since the last load,
+// reports whether certificate was reloaded.
+func (r *Reloader) Reload() (bool, error) {
+	modified, err := r.modifiedAt()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := modified.Equal(r.modified)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot load key pair")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modified = modified
+	return true, nil
+}
+
+// Close stops files polling.
+func (r *Reloader) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
+
+func (r *Reloader) modifiedAt() (time.Time, error) {
+	var modified time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		// Stat follows symlinks, which are swapped by Kubernetes on secret updates
+		fi, err := os.Stat(f)
+		if err != nil {
+			return modified, errors.Wrapf(err, "cannot stat [%s]", f)
+		}
+		if fi.ModTime().After(modified) {
+			modified = fi.ModTime()
+		}
+	}
+	return modified, nil
+}
+
+func (r *Reloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			// Previous certificate is kept when files are being rotated or are invalid
+			reloaded, err := r.Reload()
+			if err != nil {
+				r.log.WithError(err).Error("cannot reload certificate")
+				continue
+			}
+			if reloaded {
+				r.log.WithField("cert", r.certFile).Info("Reloaded certificate")
+			}
+		}
+	}
+}
+
+// NewReloader loads certificate and polls its files every interval, zero interval disables polling.
+func NewReloader(certFile string, keyFile string, interval time.Duration, logger *logrus.Logger) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		done:     make(chan struct{}),
+		log:      logger.WithField("layer", "infrastructure.tlsconfig.Reloader"),
+	}
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go r.watch(interval)
+	}
+	return r, nil
+}
@@ -0,0 +1,75 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+type Config struct {
+	// MinVersion is [1.2|1.3].
+	MinVersion string
+	// CipherSuites are TLS 1.2 cipher suite names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	// Go defaults are used when empty. TLS 1.3 suites are not configurable.
+	CipherSuites []string
+	// ClientCAFile is PEM bundle client certificates are verified against,
+	// client certificates are not requested when empty.
+	ClientCAFile string
+	// ClientAuth is [request|require], request verifies certificates only when given.
+	ClientAuth string
+}
+
+// New creates server TLS config serving certificates of reloader.
+func New(config *Config, reloader *Reloader) (*tls.Config, error) {
+	tc := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+	}
+	switch config.MinVersion {
+	case "1.2", "":
+		tc.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tc.MinVersion = tls.VersionTLS13
+	default:
+		return nil, errors.Errorf("invalid min version [%s]", config.MinVersion)
+	}
+	if len(config.CipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, s := range tls.CipherSuites() {
+			suites[s.Name] = s.ID
+		}
+		for _, name := range config.CipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, errors.Errorf("unknown or insecure cipher suite [%s]", name)
+			}
+			tc.CipherSuites = append(tc.CipherSuites, id)
+		}
+	}
+	if config.ClientCAFile == "" {
+		return tc, nil
+	}
+	pem, err := os.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read client ca")
+	}
+	tc.ClientCAs = x509.NewCertPool()
+	if !tc.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificates found in client ca [%s]", config.ClientCAFile)
+	}
+	switch config.ClientAuth {
+	case ClientAuthRequire, "":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthRequest:
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, errors.Errorf("invalid client auth [%s]", config.ClientAuth)
+	}
+	return tc, nil
+}
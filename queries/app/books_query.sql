@@ -11,8 +11,8 @@ SELECT COUNT(*)
 FROM books
 WHERE name LIKE @name
     AND description LIKE @description;
--- name: SelectBooks :many
-SELECT *
+-- name: SelectBooksPage :many
+SELECT *, COUNT(*) OVER() AS total
 FROM books
 WHERE name LIKE @name
     AND description LIKE @description
@@ -24,6 +24,6 @@ SET name = @name,
     description = @description,
     updated_at = now()
 WHERE id = @id;
--- name: DeleteBookWhereID :exec
+-- name: DeleteBookWhereID :execrows
 DELETE FROM books
 WHERE id = @id;
\ No newline at end of file
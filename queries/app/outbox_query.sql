@@ -0,0 +1,3 @@
+-- name: InsertOutboxEvent :exec
+INSERT INTO outbox(aggregate_type, aggregate_id, event_type, tenant_id, payload)
+VALUES (@aggregate_type, @aggregate_id, @event_type, @tenant_id, @payload);
@@ -0,0 +1,15 @@
+-- name: InsertTenant :one
+INSERT INTO tenants(id, schema_name)
+VALUES (@id, @schema_name)
+RETURNING *;
+-- name: SelectTenantWhereID :one
+SELECT *
+FROM tenants
+WHERE id = @id;
+-- name: SelectTenants :many
+SELECT *
+FROM tenants
+ORDER BY id;
+-- name: DeleteTenantWhereID :exec
+DELETE FROM tenants
+WHERE id = @id;